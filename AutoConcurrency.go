@@ -0,0 +1,87 @@
+package portscanner
+
+import (
+	"errors"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// congestionFactor is how many times the fastest answer seen an answer
+// may take before it counts as a sign of congestion.
+const congestionFactor = 4
+
+// aimdLimiter adapts probe concurrency the way TCP adapts its window. It
+// starts at min and doubles every window of limit probes until the first
+// sign of congestion, then grows by one per window. Congestion halves it,
+// never below min: a reset, an unreachable network or a local error, or
+// most answers of a window coming congestionFactor times slower than the
+// fastest seen so far. Timeouts are not a sign of congestion, filtered
+// ports produce them at any rate.
+type aimdLimiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active int
+	limit  int
+	min    int
+	max    int
+
+	slowStart bool
+	minRTT    time.Duration
+	window    int
+	answered  int
+	slow      int
+	failed    int
+}
+
+func newAIMDLimiter(min, max int) *aimdLimiter {
+	l := &aimdLimiter{limit: min, min: min, max: max, slowStart: true}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *aimdLimiter) acquire() {
+	l.mu.Lock()
+	for l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+	l.mu.Unlock()
+}
+
+func (l *aimdLimiter) release(took time.Duration, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	defer l.cond.Broadcast()
+
+	l.active--
+	switch {
+	case err == nil || errors.Is(err, syscall.ECONNREFUSED):
+		l.answered++
+		if l.minRTT == 0 || took < l.minRTT {
+			l.minRTT = took
+		}
+		if took > congestionFactor*l.minRTT+time.Millisecond {
+			l.slow++
+		}
+	case errors.Is(err, syscall.ECONNRESET) || isUnreachable(err) || isLocalExhaustion(err):
+		l.failed++
+	}
+
+	l.window++
+	if l.window < l.limit {
+		return
+	}
+	congested := l.failed > 0 || l.slow*2 > l.answered && l.answered > 0
+	l.window, l.answered, l.slow, l.failed = 0, 0, 0, 0
+
+	switch {
+	case congested:
+		l.limit = max(l.min, l.limit/2)
+		l.slowStart = false
+	case l.slowStart:
+		l.limit = min(l.max, l.limit*2)
+	default:
+		l.limit = min(l.max, l.limit+1)
+	}
+}
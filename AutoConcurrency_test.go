@@ -0,0 +1,34 @@
+package portscanner
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+// BenchmarkAutoConcurrency scans 1024 local ports, every dial delayed by a
+// millisecond as if the target were across a network, with a thread count
+// chosen too low and with WithAutoConcurrency starting from it.
+func BenchmarkAutoConcurrency(b *testing.B) {
+	latency := func(network, address string, c syscall.RawConn) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"fixed-2", nil},
+		{"auto-2-256", []Option{WithAutoConcurrency(2, 256)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			ps := NewPortScanner("127.0.0.1", time.Second, 2)
+			if err := ps.Apply(append(bc.opts, WithSocketOptions(latency))...); err != nil {
+				b.Fatal(err)
+			}
+			for i := 0; i < b.N; i++ {
+				ps.GetOpenedPorts(40000, 41023)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package portscanner
+
+import (
+	"sync"
+	"time"
+)
+
+// adaptiveLimiter bounds concurrency to a limit that shrinks while the
+// moving average of task durations is above slow and grows back, up to
+// max, once it drops below recovered.
+type adaptiveLimiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active int
+	limit  int
+	max    int
+	avg    time.Duration
+
+	slow      time.Duration
+	recovered time.Duration
+}
+
+// Weight of the latest sample in the exponential moving average.
+const backpressureAlpha = 0.2
+
+func newAdaptiveLimiter(max int, slow, recovered time.Duration) *adaptiveLimiter {
+	if max < 1 {
+		max = 1
+	}
+	l := &adaptiveLimiter{limit: max, max: max, slow: slow, recovered: recovered}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *adaptiveLimiter) acquire() {
+	l.mu.Lock()
+	for l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+	l.mu.Unlock()
+}
+
+func (l *adaptiveLimiter) release(took time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active--
+	if l.avg == 0 {
+		l.avg = took
+	} else {
+		l.avg = time.Duration(backpressureAlpha*float64(took) + (1-backpressureAlpha)*float64(l.avg))
+	}
+
+	if l.slow > 0 {
+		switch {
+		case l.avg > l.slow && l.limit > 1:
+			l.limit--
+		case l.avg < l.recovered && l.limit < l.max:
+			l.limit++
+		}
+	}
+	l.cond.Broadcast()
+}
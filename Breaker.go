@@ -0,0 +1,131 @@
+package portscanner
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultBreakerThreshold is how many dials in a row may fail for lack
+	// of local resources before new dials are paused.
+	DefaultBreakerThreshold = 8
+	// DefaultBreakerPause is how long dials are paused for sockets to be
+	// freed.
+	DefaultBreakerPause = 2 * time.Second
+	// maxLocalRetries bounds how often a port whose dial failed locally is
+	// probed again before its error is reported.
+	maxLocalRetries = 3
+)
+
+// isLocalExhaustion tells whether err comes from this machine running out
+// of file descriptors, ephemeral or WithSourcePorts ports, or buffers,
+// which says nothing about the port being probed.
+func isLocalExhaustion(err error) bool {
+	return errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) ||
+		errors.Is(err, syscall.EADDRNOTAVAIL) || errors.Is(err, syscall.EADDRINUSE) ||
+		errors.Is(err, syscall.ENOBUFS)
+}
+
+// localBreaker pauses every new dial once threshold dials in a row failed
+// for lack of local resources, giving sockets in TIME_WAIT or held by
+// other processes time to be released, then lets dials through again.
+type localBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	resume    chan struct{} // non-nil while tripped
+	threshold int
+	pause     time.Duration
+
+	logger *slog.Logger
+	host   string
+}
+
+func newLocalBreaker(threshold int, pause time.Duration, logger *slog.Logger, host string) *localBreaker {
+	return &localBreaker{threshold: threshold, pause: pause, logger: logger, host: host}
+}
+
+// wait blocks while the breaker is tripped, or until ctx is done.
+func (b *localBreaker) wait(ctx context.Context) error {
+	b.mu.Lock()
+	resume := b.resume
+	b.mu.Unlock()
+	if resume == nil {
+		return nil
+	}
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// record counts the outcome of a dial and trips the breaker on the
+// threshold-th local failure in a row.
+func (b *localBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !isLocalExhaustion(err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures < b.threshold || b.resume != nil {
+		return
+	}
+
+	resume := make(chan struct{})
+	b.resume = resume
+	b.log("local resources exhausted, pausing dials", err)
+	time.AfterFunc(b.pause, func() {
+		b.mu.Lock()
+		b.resume, b.failures = nil, 0
+		b.mu.Unlock()
+		close(resume)
+		b.log("resuming dials", nil)
+	})
+}
+
+func (b *localBreaker) log(msg string, err error) {
+	if b.logger == nil {
+		return
+	}
+	if err != nil {
+		b.logger.Warn(msg, "host", b.host, "error", err, "pause", b.pause)
+		return
+	}
+	b.logger.Info(msg, "host", b.host)
+}
+
+// probeGuarded is probe going through the breaker: it waits while dials
+// are paused and probes again a port whose dial failed locally, so local
+// pressure does not silently turn open ports into errors.
+func (ps PortScanner) probeGuarded(ctx context.Context, port int, breaker *localBreaker) (net.Addr, error) {
+	for attempt := 0; ; attempt++ {
+		if err := breaker.wait(ctx); err != nil {
+			return nil, err
+		}
+		local, err := ps.probe(ctx, port)
+		breaker.record(err)
+		if !isLocalExhaustion(err) || attempt == maxLocalRetries {
+			return local, err
+		}
+	}
+}
+
+// breaker builds the breaker of a scan from WithCircuitBreaker.
+func (ps PortScanner) breaker() *localBreaker {
+	threshold, pause := ps.breakerThreshold, ps.breakerPause
+	if threshold == 0 {
+		threshold = DefaultBreakerThreshold
+	}
+	if pause == 0 {
+		pause = DefaultBreakerPause
+	}
+	return newLocalBreaker(threshold, pause, ps.logger, ps.host)
+}
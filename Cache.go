@@ -0,0 +1,60 @@
+package portscanner
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+type cachedEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// ttlCache remembers values by host:port for ttl.
+type ttlCache[V any] struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cachedEntry[V]
+}
+
+func newTTLCache[V any](ttl time.Duration) *ttlCache[V] {
+	return &ttlCache[V]{ttl: ttl, entries: map[string]cachedEntry[V]{}}
+}
+
+func (c *ttlCache[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+func (c *ttlCache[V]) put(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cachedEntry[V]{value: value, expires: time.Now().Add(c.ttl)}
+}
+
+// describeCache remembers descriptions, see WithDescribeCache.
+type describeCache = ttlCache[predictors.Match]
+
+func newDescribeCache(ttl time.Duration) *describeCache {
+	return newTTLCache[predictors.Match](ttl)
+}
+
+// openResult is the outcome of IsOpenE, as remembered by WithOpenCache.
+type openResult struct {
+	open bool
+	err  error
+}
@@ -0,0 +1,100 @@
+package portscanner
+
+const (
+	CategoryWeb          = "web"
+	CategoryDatabase     = "database"
+	CategoryRemoteAccess = "remote-access"
+	CategoryMail         = "mail"
+	CategoryFileSharing  = "file-sharing"
+	CategoryDirectory    = "directory"
+	CategoryNetwork      = "network"
+	CategoryMessaging    = "messaging"
+	CategoryMonitoring   = "monitoring"
+	CategoryMedia        = "media"
+	CategoryPrinting     = "printing"
+	CategoryOther        = "other"
+)
+
+// PORT_CATEGORIES groups the ports of KNOWN_PORTS by the kind of service
+// they usually carry. Ports missing from it belong to CategoryOther.
+var PORT_CATEGORIES = map[int]string{
+	21:    CategoryFileSharing,
+	22:    CategoryRemoteAccess,
+	23:    CategoryRemoteAccess,
+	25:    CategoryMail,
+	53:    CategoryNetwork,
+	66:    CategoryDatabase,
+	69:    CategoryFileSharing,
+	80:    CategoryWeb,
+	88:    CategoryDirectory,
+	109:   CategoryMail,
+	110:   CategoryMail,
+	118:   CategoryDatabase,
+	123:   CategoryNetwork,
+	137:   CategoryFileSharing,
+	139:   CategoryFileSharing,
+	143:   CategoryMail,
+	150:   CategoryDatabase,
+	194:   CategoryMessaging,
+	389:   CategoryDirectory,
+	443:   CategoryWeb,
+	445:   CategoryFileSharing,
+	465:   CategoryMail,
+	554:   CategoryMedia,
+	587:   CategoryMail,
+	631:   CategoryPrinting,
+	636:   CategoryDirectory,
+	993:   CategoryMail,
+	995:   CategoryMail,
+	1080:  CategoryNetwork,
+	1433:  CategoryDatabase,
+	1434:  CategoryDatabase,
+	1883:  CategoryMessaging,
+	2181:  CategoryDatabase,
+	2375:  CategoryRemoteAccess,
+	2376:  CategoryRemoteAccess,
+	3306:  CategoryDatabase,
+	3389:  CategoryRemoteAccess,
+	3396:  CategoryPrinting,
+	3535:  CategoryMail,
+	5432:  CategoryDatabase,
+	5800:  CategoryRemoteAccess,
+	5900:  CategoryRemoteAccess,
+	6379:  CategoryDatabase,
+	6667:  CategoryMessaging,
+	6697:  CategoryMessaging,
+	8080:  CategoryWeb,
+	8086:  CategoryDatabase,
+	8443:  CategoryWeb,
+	8883:  CategoryMessaging,
+	9090:  CategoryMonitoring,
+	9092:  CategoryMessaging,
+	9100:  CategoryMonitoring,
+	9160:  CategoryDatabase,
+	9200:  CategoryDatabase,
+	9418:  CategoryFileSharing,
+	11211: CategoryDatabase,
+	27017: CategoryDatabase,
+	28017: CategoryWeb,
+	50051: CategoryWeb,
+}
+
+func portCategory(port int) string {
+	if category, exists := PORT_CATEGORIES[port]; exists {
+		return category
+	}
+	return CategoryOther
+}
+
+// ResultsByCategory groups the results of the report by service category.
+func (r Report) ResultsByCategory() map[string][]ScanResult {
+	grouped := map[string][]ScanResult{}
+	for _, res := range r.Results {
+		category := res.Category
+		if category == "" {
+			category = portCategory(res.Port)
+		}
+		grouped[category] = append(grouped[category], res)
+	}
+	return grouped
+}
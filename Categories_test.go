@@ -0,0 +1,16 @@
+package portscanner
+
+import "testing"
+
+func TestEveryKnownPortHasCategory(t *testing.T) {
+	for port, label := range KNOWN_PORTS {
+		if _, ok := PORT_CATEGORIES[port]; !ok {
+			t.Errorf("port %d (%s) has no category", port, label)
+		}
+	}
+	for port := range PORT_CATEGORIES {
+		if _, ok := KNOWN_PORTS[port]; !ok {
+			t.Errorf("port %d has a category but no KNOWN_PORTS label", port)
+		}
+	}
+}
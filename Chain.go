@@ -0,0 +1,179 @@
+package portscanner
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// DescribeContext is the port a describe step identifies, along with what
+// the scanner knows of it so far.
+type DescribeContext struct {
+	Port int
+	// Session is shared by every step, so responses it caches, such as the
+	// greeting or HTTP headers, are only fetched once.
+	Session *predictors.Session
+	// Assumed is the KNOWN_PORTS label of Port, or UNKNOWN.
+	Assumed string
+	// Detected is the protocol active detection found talking to the
+	// port, e.g. "HTTPS", once DescribeActive ran and found one.
+	Detected string
+
+	scanner PortScanner
+	// step is the position in the chain, from 1, of the step that found
+	// the match, or 0.
+	step int
+	// mismatch notes a web port speaking the other of HTTP and HTTPS than
+	// its number says, as found by DescribeActive.
+	mismatch string
+}
+
+// DescribeStep is one way of identifying a port. It returns a match, or
+// one that is not found or reads UNKNOWN to hand the port to the next
+// step. Steps set the Source of their match.
+type DescribeStep func(dc *DescribeContext) predictors.Match
+
+var (
+	// DescribeProbes runs the WithProbe payloads registered for the port.
+	DescribeProbes DescribeStep = describeProbes
+	// DescribePortPredictors asks the predictors tied to the port, except
+	// on HTTP ports, which DescribeActive checks as HTTP first.
+	DescribePortPredictors DescribeStep = describePortPredictors
+	// DescribeActive reads the greeting, attempts a TLS handshake and
+	// tries the HTTP predictors, and records the protocol it found. On web
+	// ports it tries cleartext HTTP as well as TLS, and flags a port
+	// serving the other one than its number says.
+	DescribeActive DescribeStep = describeActive
+	// DescribeTLS only attempts the TLS handshake of DescribeActive, for
+	// chains that want it earlier.
+	DescribeTLS DescribeStep = describeTLS
+	// DescribeMySQL reads the server version from the handshake of ports
+	// labelled MySQL.
+	DescribeMySQL DescribeStep = describeMySQL
+	// DescribeKnownPorts falls back to the KNOWN_PORTS label.
+	DescribeKnownPorts DescribeStep = describeKnownPorts
+)
+
+// DefaultDescribeChain is the order ports are identified in unless
+// WithDescribeChain says otherwise: what the port actually speaks wins
+// over what its number suggests.
+var DefaultDescribeChain = []DescribeStep{
+	DescribeProbes,
+	DescribePortPredictors,
+	DescribeActive,
+	DescribeMySQL,
+	DescribeKnownPorts,
+}
+
+// describeChain runs the steps in order and returns the first match one
+// of them finds.
+func (ps PortScanner) describeChain(dc *DescribeContext) predictors.Match {
+	steps := ps.describeSteps
+	if steps == nil {
+		steps = DefaultDescribeChain
+	}
+	for i, step := range steps {
+		if match := step(dc); match.Found() && match.Description != UNKNOWN {
+			dc.step = i + 1
+			return match
+		}
+	}
+	return predictors.Match{}
+}
+
+// maxEvidence caps how much of what a service sent is logged with each
+// describe decision.
+const maxEvidence = 256
+
+// auditDescribe logs how the port of dc came to be described as match:
+// the step and source that identified it, what the service sent and the
+// final label, for the record of an assessment.
+func (ps PortScanner) auditDescribe(dc *DescribeContext, match predictors.Match) {
+	if ps.logger == nil {
+		return
+	}
+	evidence := dc.Session.Evidence()
+	if len(evidence) > maxEvidence {
+		evidence = evidence[:maxEvidence] + "..."
+	}
+	ps.logger.Info("port described",
+		"host", ps.host, "port", dc.Port,
+		"step", dc.step, "source", match.Source,
+		"protocol", dc.Detected, "assumed", dc.Assumed,
+		"confidence", match.Confidence, "evidence", evidence,
+		"label", match.Description)
+}
+
+func describeProbes(dc *DescribeContext) predictors.Match {
+	match := dc.scanner.runProbes(dc.Session, dc.Port)
+	match.Source = SourceProbe
+	return match
+}
+
+func describePortPredictors(dc *DescribeContext) predictors.Match {
+	if dc.scanner.IsHttp(dc.Port) || dc.scanner.IsHttps(dc.Port) {
+		return predictors.Match{}
+	}
+	match := dc.scanner.bestMatch(dc.Session, dc.scanner.portPredictors(dc.Port))
+	match.Source = SourcePredictor
+	return match
+}
+
+func describeActive(dc *DescribeContext) predictors.Match {
+	httpsPort := dc.scanner.IsHttps(dc.Port)
+	httpPort := dc.scanner.IsHttp(dc.Port) || httpsPort
+	detected, match := dc.scanner.detectActive(dc.Session, httpPort)
+	switch {
+	case !match.Found() && httpsPort:
+		dc.Session.TLS = false
+		dc.Session.ResetHTTP()
+		detected, match = dc.scanner.detectHTTP(dc.Session)
+	case detected == "HTTP" && match.Confidence < 1 && !httpsPort:
+		// Many TLS servers answer a cleartext request with an HTTP
+		// error, which is all a generic match may be.
+		dc.Session.ResetHTTP()
+		if tlsDetected, tlsMatch := dc.scanner.detectTLS(dc.Session); tlsMatch.Found() {
+			detected, match = tlsDetected, tlsMatch
+		}
+	}
+	dc.Detected = detected
+
+	switch {
+	case httpsPort && detected == "HTTP":
+		dc.mismatch = "port " + strconv.Itoa(dc.Port) + ": cleartext HTTP served on HTTPS port"
+	case httpPort && !httpsPort && (detected == "HTTPS" || detected == "TLS"):
+		dc.mismatch = "port " + strconv.Itoa(dc.Port) + ": " + detected + " served on HTTP port"
+	}
+	match.Source = SourceBanner
+	if strings.HasPrefix(detected, "HTTP") && match.Confidence == 1 {
+		match.Source = SourcePredictor
+	}
+	return match
+}
+
+func describeTLS(dc *DescribeContext) predictors.Match {
+	detected, match := dc.scanner.detectTLS(dc.Session)
+	if match.Found() {
+		dc.Detected = detected
+	}
+	match.Source = SourceBanner
+	return match
+}
+
+func describeMySQL(dc *DescribeContext) predictors.Match {
+	if dc.Assumed != "MySQL" {
+		return predictors.Match{}
+	}
+	match := predictors.Certain(dc.scanner.getMySQLVersion(dc.Session, dc.Assumed))
+	if match.Source = SourceBanner; match.Description == dc.Assumed {
+		match.Source = SourceKnownPorts
+	}
+	return match
+}
+
+func describeKnownPorts(dc *DescribeContext) predictors.Match {
+	match := predictors.Certain(dc.Assumed)
+	match.Source = SourceKnownPorts
+	return match
+}
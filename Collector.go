@@ -0,0 +1,46 @@
+package portscanner
+
+import "sync"
+
+// maxCollectorShards caps the shards of a collector; past a few hundred
+// the merge costs more than the contention saved.
+const maxCollectorShards = 256
+
+// collector gathers the outcomes reported by concurrent probes. Items are
+// spread over shards by port, each with its own lock, so workers rarely
+// wait on each other; the shards are merged once probing is over.
+type collector[T any] struct {
+	shards []collectorShard[T]
+}
+
+type collectorShard[T any] struct {
+	mu    sync.Mutex
+	items []T
+	// Keeps neighbouring shards off the same cache line.
+	_ [64]byte
+}
+
+func newCollector[T any](workers int) *collector[T] {
+	return &collector[T]{shards: make([]collectorShard[T], min(max(workers, 1), maxCollectorShards))}
+}
+
+func (c *collector[T]) add(port int, item T) {
+	shard := &c.shards[port%len(c.shards)]
+	shard.mu.Lock()
+	shard.items = append(shard.items, item)
+	shard.mu.Unlock()
+}
+
+// items merges the shards. It must only be called once every add has
+// returned.
+func (c *collector[T]) items() []T {
+	n := 0
+	for i := range c.shards {
+		n += len(c.shards[i].items)
+	}
+	merged := make([]T, 0, n)
+	for i := range c.shards {
+		merged = append(merged, c.shards[i].items...)
+	}
+	return merged
+}
@@ -0,0 +1,93 @@
+package portscanner
+
+import (
+	"net"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const benchmarkThreads = 1024
+
+func TestCollectorKeepsEveryItem(t *testing.T) {
+	c := newCollector[int](benchmarkThreads)
+	var wg sync.WaitGroup
+	for port := MinPort; port <= MaxPort; port++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.add(port, port)
+		}()
+	}
+	wg.Wait()
+
+	items := c.items()
+	slices.Sort(items)
+	if len(items) != MaxPort || items[0] != MinPort || items[len(items)-1] != MaxPort {
+		t.Fatalf("collected %d items, want every port once", len(items))
+	}
+	for i := 1; i < len(items); i++ {
+		if items[i] != items[i-1]+1 {
+			t.Fatalf("port %d collected after %d", items[i], items[i-1])
+		}
+	}
+}
+
+// BenchmarkCollector has benchmarkThreads workers report 64 outcomes each,
+// into a collector and into a slice behind a single mutex as before. The
+// contention the shards avoid only shows with several CPUs, see -cpu.
+func BenchmarkCollector(b *testing.B) {
+	const perWorker = 64
+	run := func(b *testing.B, add func(port int)) {
+		for i := 0; i < b.N; i++ {
+			var wg sync.WaitGroup
+			for w := 0; w < benchmarkThreads; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for j := 0; j < perWorker; j++ {
+						add(w*perWorker + j)
+					}
+				}(w)
+			}
+			wg.Wait()
+		}
+	}
+
+	b.Run("sharded", func(b *testing.B) {
+		c := newCollector[int](benchmarkThreads)
+		run(b, func(port int) { c.add(port, port) })
+	})
+	b.Run("mutex", func(b *testing.B) {
+		var mu sync.Mutex
+		var items []int
+		run(b, func(port int) {
+			mu.Lock()
+			items = append(items, port)
+			mu.Unlock()
+		})
+	})
+}
+
+// BenchmarkGetOpenedPorts scans the 2048 ports from a local listener on
+// benchmarkThreads threads, every dial delayed by a millisecond as if the
+// target were across a network.
+func BenchmarkGetOpenedPorts(b *testing.B) {
+	port := listen(b, func(conn net.Conn) {})
+	ps := NewPortScanner("127.0.0.1", time.Second, benchmarkThreads)
+	latency := func(network, address string, c syscall.RawConn) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+	if err := ps.Apply(WithSocketOptions(latency)); err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		if open := ps.GetOpenedPorts(port, min(port+2047, MaxPort)); len(open) == 0 || open[0] != port {
+			b.Fatalf("GetOpenedPorts = %v, missing %d", open, port)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package portscanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+	"github.com/elchemista/port-scanner/predictors/ci"
+	"github.com/elchemista/port-scanner/predictors/docker"
+	"github.com/elchemista/port-scanner/predictors/git"
+	"github.com/elchemista/port-scanner/predictors/grpc"
+	"github.com/elchemista/port-scanner/predictors/influxdb"
+	"github.com/elchemista/port-scanner/predictors/irc"
+	"github.com/elchemista/port-scanner/predictors/kafka"
+	"github.com/elchemista/port-scanner/predictors/ldap"
+	"github.com/elchemista/port-scanner/predictors/mail"
+	"github.com/elchemista/port-scanner/predictors/mongodb"
+	"github.com/elchemista/port-scanner/predictors/mqtt"
+	"github.com/elchemista/port-scanner/predictors/ntp"
+	"github.com/elchemista/port-scanner/predictors/postgres"
+	"github.com/elchemista/port-scanner/predictors/prometheus"
+	"github.com/elchemista/port-scanner/predictors/redis"
+	"github.com/elchemista/port-scanner/predictors/rtsp"
+	"github.com/elchemista/port-scanner/predictors/socks"
+	"github.com/elchemista/port-scanner/predictors/webserver"
+	"github.com/elchemista/port-scanner/predictors/zookeeper"
+)
+
+// Config is a scan profile, as read by NewFromConfig:
+//
+//	{
+//	  "host": "db.internal",
+//	  "timeout": "2s",
+//	  "threads": 100,
+//	  "predictors": ["nginx", "redis"],
+//	  "ports": "1-1024,6379",
+//	  "exclude": "25"
+//	}
+//
+// host is optional and can be set later with SetHost. An empty predictors
+// list keeps the default ones. ports defaults to every port. Profiles are
+// JSON only; there is no YAML support.
+type Config struct {
+	Host       string   `json:"host,omitempty"`
+	Timeout    string   `json:"timeout"`
+	Threads    int      `json:"threads"`
+	Predictors []string `json:"predictors,omitempty"`
+	Ports      string   `json:"ports,omitempty"`
+	Exclude    string   `json:"exclude,omitempty"`
+}
+
+// PREDICTORS maps the names usable in Config.Predictors to the predictors
+// they stand for.
+var PREDICTORS = map[string]func() predictors.Predictor{
+	"apache":     func() predictors.Predictor { return &webserver.ApachePredictor{} },
+	"nginx":      func() predictors.Predictor { return &webserver.NginxPredictor{} },
+	"ldap":       func() predictors.Predictor { return &ldap.LDAPPredictor{} },
+	"socks":      func() predictors.Predictor { return &socks.SocksPredictor{} },
+	"git":        func() predictors.Predictor { return &git.GitPredictor{} },
+	"irc":        func() predictors.Predictor { return &irc.IRCPredictor{} },
+	"zookeeper":  func() predictors.Predictor { return &zookeeper.ZookeeperPredictor{} },
+	"redis":      func() predictors.Predictor { return &redis.RedisPredictor{} },
+	"mongodb":    func() predictors.Predictor { return &mongodb.MongoDBPredictor{} },
+	"docker":     func() predictors.Predictor { return &docker.DockerPredictor{} },
+	"influxdb":   func() predictors.Predictor { return &influxdb.InfluxDBPredictor{} },
+	"grpc":       func() predictors.Predictor { return &grpc.GRPCPredictor{} },
+	"smtp":       func() predictors.Predictor { return &mail.SMTPPredictor{} },
+	"imap":       func() predictors.Predictor { return &mail.IMAPPredictor{} },
+	"pop3":       func() predictors.Predictor { return &mail.POP3Predictor{} },
+	"ntp":        func() predictors.Predictor { return &ntp.NTPPredictor{} },
+	"prometheus": func() predictors.Predictor { return &prometheus.PrometheusPredictor{} },
+	"kafka":      func() predictors.Predictor { return &kafka.KafkaPredictor{} },
+	"rtsp":       func() predictors.Predictor { return &rtsp.RTSPPredictor{} },
+	"ci":         func() predictors.Predictor { return &ci.CIPredictor{} },
+	"postgres":   func() predictors.Predictor { return &postgres.PostgresPredictor{} },
+	"mqtt":       func() predictors.Predictor { return &mqtt.MQTTPredictor{} },
+}
+
+// NewFromConfig reads a JSON Config from r and builds the scanner it
+// describes. It also returns the ports the profile scans, ports minus
+// exclude in order, to hand to ScanPorts. Unknown fields and invalid
+// values are reported as errors naming the offending field.
+func NewFromConfig(r io.Reader) (*PortScanner, []int, error) {
+	var cfg Config
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, nil, fmt.Errorf("portscanner: config: %w", err)
+	}
+	return cfg.Build()
+}
+
+// Build validates the config and builds the scanner it describes, along
+// with the ports to scan.
+func (cfg Config) Build() (*PortScanner, []int, error) {
+	timeout, err := time.ParseDuration(cfg.Timeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("portscanner: config: timeout %q: %w", cfg.Timeout, err)
+	}
+	if timeout <= 0 {
+		return nil, nil, fmt.Errorf("portscanner: config: timeout must be positive, got %s", timeout)
+	}
+	if cfg.Threads < 1 {
+		return nil, nil, fmt.Errorf("portscanner: config: threads must be at least 1, got %d", cfg.Threads)
+	}
+
+	ports := portRange(MinPort, MaxPort)
+	if cfg.Ports != "" {
+		if ports, err = ParsePortSpec(cfg.Ports); err != nil {
+			return nil, nil, fmt.Errorf("portscanner: config: ports: %w", err)
+		}
+	}
+	if cfg.Exclude != "" {
+		excluded, err := ParsePortSpec(cfg.Exclude)
+		if err != nil {
+			return nil, nil, fmt.Errorf("portscanner: config: exclude: %w", err)
+		}
+		ports = slices.DeleteFunc(ports, func(port int) bool {
+			_, found := slices.BinarySearch(excluded, port)
+			return found
+		})
+	}
+	if len(ports) == 0 {
+		return nil, nil, fmt.Errorf("portscanner: config: exclude leaves no port to scan")
+	}
+
+	ps := NewPortScanner(cfg.Host, timeout, cfg.Threads)
+	if len(cfg.Predictors) > 0 {
+		ps.predictors = nil
+		for _, name := range cfg.Predictors {
+			build, ok := PREDICTORS[name]
+			if !ok {
+				return nil, nil, fmt.Errorf("portscanner: config: unknown predictor %q", name)
+			}
+			ps.RegisterPredictor(build())
+		}
+	}
+	return ps, ports, nil
+}
@@ -0,0 +1,26 @@
+package portscanner
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestNewFromConfigPortsScan(t *testing.T) {
+	open := listen(t, func(conn net.Conn) { conn.Read(make([]byte, 1)) })
+	profile := fmt.Sprintf(`{"host": "127.0.0.1", "timeout": "1s", "threads": 4, "ports": "%d-%d", "exclude": "%d"}`, open, open+2, open+1)
+
+	ps, ports, err := NewFromConfig(strings.NewReader(profile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{open, open + 2}; fmt.Sprint(ports) != fmt.Sprint(want) {
+		t.Fatalf("ports = %v, want %v", ports, want)
+	}
+	ps.TogglePredictor(false)
+	report := ps.ScanPorts(ports)
+	if len(report.Results) != 1 || report.Results[0].Port != open {
+		t.Fatalf("Results = %v, want port %d open", report.Results, open)
+	}
+}
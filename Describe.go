@@ -0,0 +1,72 @@
+package portscanner
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// DescribePorts describes ports already known to be open, using up to
+// threads workers, or as many as WithDescribeConcurrency allows. With
+// WithDescribeBackpressure set, the number of workers shrinks while the
+// target answers slowly.
+func (ps PortScanner) DescribePorts(ports []int) map[int]string {
+	matches, _ := ps.describePortMatches(ports, nil)
+	results := make(map[int]string, len(matches))
+	for port, match := range matches {
+		results[port] = match.Description
+	}
+	return results
+}
+
+// describeWorkers is the most ports described at once.
+func (ps PortScanner) describeWorkers() int {
+	if ps.describeConcurrency > 0 {
+		return ps.describeConcurrency
+	}
+	return ps.threads
+}
+
+// describePortMatches describes ports, returning the panics recovered on
+// the way; the ports concerned get an empty match. done, if not nil, is
+// called with each match as soon as it is known, possibly concurrently.
+func (ps PortScanner) describePortMatches(ports []int, done func(port int, match predictors.Match)) (map[int]predictors.Match, []*PortPanic) {
+	results := make(map[int]predictors.Match, len(ports))
+	var panics []*PortPanic
+	var mu sync.Mutex
+	wg := sync.WaitGroup{}
+	limiter := newAdaptiveLimiter(ps.describeWorkers(), ps.describeSlow, ps.describeRecovered)
+
+	for _, port := range ports {
+		limiter.acquire()
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			began := time.Now()
+			match, panicked := ps.safeDescribe(context.Background(), port)
+			limiter.release(time.Since(began))
+			if done != nil {
+				done(port, match)
+			}
+
+			mu.Lock()
+			results[port] = match
+			if panicked != nil {
+				panics = append(panics, panicked)
+			}
+			mu.Unlock()
+		}(port)
+	}
+
+	wg.Wait()
+	return results, panics
+}
+
+// ScanAndDescribe finds the open ports in [start, end] and describes them.
+// Unlike calling DescribePort for each open port, it does not dial every
+// port again just to confirm it is open.
+func (ps PortScanner) ScanAndDescribe(start, end int) map[int]string {
+	return ps.DescribePorts(ps.GetOpenedPorts(start, end))
+}
@@ -0,0 +1,65 @@
+package portscanner
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fragileTarget is a server whose answers slow down with the number of
+// connections it handles at once, as an overloaded host does.
+type fragileTarget struct {
+	active atomic.Int64
+	mu     sync.Mutex
+	delays time.Duration
+	served int64
+}
+
+func (ft *fragileTarget) serve(conn net.Conn) {
+	active := ft.active.Add(1)
+	defer ft.active.Add(-1)
+	delay := time.Millisecond + time.Duration(active)*time.Millisecond
+	ft.mu.Lock()
+	ft.delays += delay
+	ft.served++
+	ft.mu.Unlock()
+
+	time.Sleep(delay)
+	conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+	conn.Read(make([]byte, 1))
+}
+
+// BenchmarkDescribePorts describes the ports of a fragile target with and
+// without WithDescribeBackpressure. Besides the time it takes, it reports
+// the health of the target: how long its answers took on average.
+func BenchmarkDescribePorts(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"fixed", nil},
+		{"backpressure", []Option{WithDescribeBackpressure(10*time.Millisecond, 5*time.Millisecond)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			target := &fragileTarget{}
+			ports := make([]int, 256)
+			for i := range ports {
+				ports[i] = listen(b, target.serve)
+			}
+			ps := NewPortScanner("127.0.0.1", time.Second, 32)
+			if err := ps.Apply(append(bc.opts, WithReadTimeout(time.Second))...); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ps.DescribePorts(ports)
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(target.delays.Milliseconds())/float64(target.served), "answer-ms")
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package portscanner
+
+import (
+	"crypto/tls"
+	"io"
+	"strings"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// Greetings that identify a protocol, sent by the server as soon as it
+// accepts a connection.
+var bannerPrefixes = []struct {
+	prefix   string
+	protocol string
+}{
+	{"SSH-", "SSH"},
+	{"+OK", "POP3"},
+	{"* OK", "IMAP"},
+	{"RFB ", "VNC"},
+}
+
+// Labels of KNOWN_PORTS whose services are served over HTTP.
+var httpServices = map[string]bool{
+	"CUPS":                true,
+	"Docker API":          true,
+	"Docker API over TLS": true,
+	"Elasticsearch":       true,
+	"InfluxDB":            true,
+	"MongoDB Web Admin":   true,
+	"Prometheus":          true,
+	"VNC Remote Desktop":  true,
+	"node_exporter":       true,
+}
+
+// detectBanner names the protocol behind a greeting, or returns "" when
+// the greeting is unknown. FTP and SMTP both greet with 220 and are told
+// apart by the text that follows, when they can be at all.
+func detectBanner(banner string) (protocol, firstLine string) {
+	firstLine, _, _ = strings.Cut(banner, "\n")
+	firstLine = strings.TrimSpace(firstLine)
+
+	for _, b := range bannerPrefixes {
+		if strings.HasPrefix(firstLine, b.prefix) {
+			return b.protocol, firstLine
+		}
+	}
+	if strings.HasPrefix(firstLine, "220") {
+		upper := strings.ToUpper(firstLine)
+		switch {
+		case strings.Contains(upper, "SMTP"):
+			return "SMTP", firstLine
+		case strings.Contains(upper, "FTP"):
+			return "FTP", firstLine
+		}
+	}
+	return "", firstLine
+}
+
+// detectActive identifies the service on the session's port by talking
+// to it rather than trusting the port number: it reads the greeting,
+// attempts a TLS handshake and tries the generic HTTP predictors, the
+// latter first on HTTP ports. TLS comes before plain HTTP as many TLS
+// servers answer a cleartext request with an HTTP error. It returns the
+// protocol it found along with the match.
+func (ps PortScanner) detectActive(session *predictors.Session, httpFirst bool) (string, predictors.Match) {
+	steps := []func(*predictors.Session) (string, predictors.Match){ps.detectGreeting, ps.detectTLS, ps.detectHTTP}
+	if httpFirst {
+		steps = []func(*predictors.Session) (string, predictors.Match){ps.detectHTTP, ps.detectGreeting, ps.detectTLS}
+	}
+	for _, step := range steps {
+		if protocol, match := step(session); match.Found() {
+			return protocol, match
+		}
+	}
+	return "", predictors.Match{}
+}
+
+func (ps PortScanner) detectGreeting(session *predictors.Session) (string, predictors.Match) {
+	banner, err := session.Banner()
+	if err != nil || banner == "" {
+		return "", predictors.Match{}
+	}
+	protocol, firstLine := detectBanner(banner)
+	if protocol == "" {
+		return "", predictors.Match{}
+	}
+	match := predictors.Certain(protocol + " (" + firstLine + ")")
+	if protocol == "SSH" {
+		match.Software = predictors.ParseSSHBanner(firstLine)
+	}
+	return protocol, match
+}
+
+// detectTLS attempts a handshake and, when the port speaks TLS, describes
+// it through the HTTP predictors over TLS, or by its certificate.
+func (ps PortScanner) detectTLS(session *predictors.Session) (string, predictors.Match) {
+	if session.TLS {
+		return "", predictors.Match{}
+	}
+	conn, err := session.DialTLS()
+	if err != nil {
+		return "", predictors.Match{}
+	}
+	state := conn.(*tls.Conn).ConnectionState()
+	conn.Close()
+
+	session.TLS = true
+	if protocol, match := ps.detectHTTP(session); match.Found() {
+		return protocol, match
+	}
+
+	description := "TLS"
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		name := cert.Subject.CommonName
+		if name == "" && len(cert.DNSNames) > 0 {
+			name = cert.DNSNames[0]
+		}
+		if name != "" {
+			description += " (certificate: " + name + ")"
+		}
+	}
+	return "TLS", predictors.Match{Description: description, Confidence: 0.5}
+}
+
+func (ps PortScanner) detectHTTP(session *predictors.Session) (string, predictors.Match) {
+	protocol := "HTTP"
+	if session.TLS {
+		protocol = "HTTPS"
+	}
+	if match := ps.bestMatch(session, ps.genericPredictors()); match.Found() {
+		return protocol, match
+	}
+	// No predictor knows the server, but it does speak HTTP. The request
+	// comes from the session cache when a predictor already sent it.
+	if resp, err := session.HTTP("HEAD", "/"); err == nil && strings.HasPrefix(resp, "HTTP/") {
+		return protocol, predictors.Match{Description: protocol, Confidence: 0.5}
+	}
+	return "", predictors.Match{}
+}
+
+// conflicts tells whether a detected protocol is not the one the port
+// label announces.
+func conflicts(detected, label string) bool {
+	if detected == "" || label == UNKNOWN {
+		return false
+	}
+	if strings.HasPrefix(detected, "HTTP") && httpServices[label] {
+		return false
+	}
+	if detected == "TLS" {
+		upper := strings.ToUpper(label)
+		return !strings.Contains(upper, "SSL") && !strings.Contains(upper, "TLS") && !strings.Contains(upper, "HTTPS")
+	}
+	return !strings.Contains(strings.ToUpper(label), detected)
+}
+
+// customProbe is a probe registered with WithProbe.
+type customProbe struct {
+	payload []byte
+	match   func([]byte) string
+}
+
+// runProbes sends the WithProbe payloads registered for the session's
+// port, in order, and returns the first description one of them matches.
+func (ps PortScanner) runProbes(session *predictors.Session, port int) (match predictors.Match) {
+	defer func() {
+		if r := recover(); r != nil {
+			if ps.logger != nil {
+				ps.logger.Error("probe matcher panicked", "host", session.Host, "panic", r)
+			}
+			match = predictors.Match{}
+		}
+	}()
+
+	for _, probe := range ps.probes[port] {
+		if match = predictors.Certain(probe.run(session)); match.Found() {
+			return match
+		}
+	}
+	return predictors.Match{}
+}
+
+func (probe customProbe) run(session *predictors.Session) string {
+	conn, err := session.Dial()
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	if len(probe.payload) > 0 {
+		if _, err := conn.Write(probe.payload); err != nil {
+			return ""
+		}
+	}
+	resp, err := io.ReadAll(session.Limit(conn))
+	if len(resp) == 0 && err != nil && !isTimeout(err) {
+		return ""
+	}
+	return probe.match(resp)
+}
+
+// payloadKind tells whether data looks like a text or a binary protocol:
+// text when nearly all of its bytes are printable ASCII or whitespace.
+func payloadKind(data string) string {
+	printable := 0
+	for i := 0; i < len(data); i++ {
+		if c := data[i]; c >= 0x20 && c < 0x7f || c == '\r' || c == '\n' || c == '\t' {
+			printable++
+		}
+	}
+	if printable*10 >= len(data)*9 {
+		return "text"
+	}
+	return "binary"
+}
@@ -0,0 +1,113 @@
+package portscanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+)
+
+type ServiceChange struct {
+	Port int
+	// Protocol is ProtocolTCP or ProtocolUDP.
+	Protocol string
+	Before   string
+	After    string
+}
+
+type ReportDiff struct {
+	Opened  []ScanResult
+	Closed  []ScanResult
+	Changed []ServiceChange
+}
+
+func (d ReportDiff) Empty() bool {
+	return len(d.Opened) == 0 && len(d.Closed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares two reports of the same host. A port open over TCP and UDP
+// counts as two results, compared separately. It does not depend on the
+// order of the results in either report; every slice of the returned diff
+// is sorted by port, then protocol.
+func Diff(before, after Report) ReportDiff {
+	prev := resultsByPort(before)
+	next := resultsByPort(after)
+
+	var diff ReportDiff
+	for key, res := range next {
+		old, existed := prev[key]
+		if !existed {
+			diff.Opened = append(diff.Opened, res)
+			continue
+		}
+		if old.Service != res.Service {
+			diff.Changed = append(diff.Changed, ServiceChange{
+				Port:     key.port,
+				Protocol: key.protocol,
+				Before:   old.Service,
+				After:    res.Service,
+			})
+		}
+	}
+	for key, res := range prev {
+		if _, exists := next[key]; !exists {
+			diff.Closed = append(diff.Closed, res)
+		}
+	}
+
+	sortResults(diff.Opened)
+	sortResults(diff.Closed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		a, b := diff.Changed[i], diff.Changed[j]
+		return a.Port < b.Port || a.Port == b.Port && a.Protocol < b.Protocol
+	})
+	return diff
+}
+
+// resultKey identifies a result within a report.
+type resultKey struct {
+	protocol string
+	port     int
+}
+
+func resultsByPort(r Report) map[resultKey]ScanResult {
+	m := make(map[resultKey]ScanResult, len(r.Results))
+	for _, res := range r.Results {
+		m[resultKey{resultProtocol(res), res.Port}] = res
+	}
+	return m
+}
+
+// resultProtocol is the protocol of res, results without one being TCP.
+func resultProtocol(res ScanResult) string {
+	if res.Protocol == "" {
+		return ProtocolTCP
+	}
+	return res.Protocol
+}
+
+func sortResults(results []ScanResult) {
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		return a.Port < b.Port || a.Port == b.Port && resultProtocol(a) < resultProtocol(b)
+	})
+}
+
+// Fingerprint hashes what the report exposes, the protocol, port, state
+// and service of each result, and nothing else: timings, metrics and the
+// order of the results do not change it. Two reports of a host with the
+// same fingerprint expose the same services, so periodic scans can be
+// compared without diffing them.
+func (r Report) Fingerprint() string {
+	lines := make([]string, 0, len(r.Results))
+	for _, res := range r.Results {
+		lines = append(lines, fmt.Sprintf("%s/%d %s %s", resultProtocol(res), res.Port, res.State, res.Service))
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		fmt.Fprintln(h, line)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,30 @@
+package portscanner
+
+import "testing"
+
+func TestDiffKeepsProtocolsApart(t *testing.T) {
+	before := Report{Host: "ns", Results: []ScanResult{
+		{Port: 53, State: PortOpen, Service: "DNS", Protocol: ProtocolTCP},
+		{Port: 53, State: PortOpen, Service: "DNS", Protocol: ProtocolUDP},
+		{Port: 123, State: PortOpen, Service: "NTP", Protocol: ProtocolUDP},
+	}}
+	after := Report{Host: "ns", Results: []ScanResult{
+		{Port: 53, State: PortOpen, Service: "DNS", Protocol: ProtocolTCP},
+		{Port: 53, State: PortOpen, Service: "DNS (BIND 9.18)", Protocol: ProtocolUDP},
+		{Port: 123, State: PortOpen, Service: "NTP", Protocol: ProtocolTCP},
+	}}
+
+	diff := Diff(before, after)
+	if len(diff.Changed) != 1 || diff.Changed[0] != (ServiceChange{Port: 53, Protocol: ProtocolUDP, Before: "DNS", After: "DNS (BIND 9.18)"}) {
+		t.Fatalf("Changed = %+v, want the UDP side of 53 only", diff.Changed)
+	}
+	if len(diff.Opened) != 1 || diff.Opened[0].Port != 123 || diff.Opened[0].Protocol != ProtocolTCP {
+		t.Fatalf("Opened = %+v, want 123/tcp", diff.Opened)
+	}
+	if len(diff.Closed) != 1 || diff.Closed[0].Port != 123 || diff.Closed[0].Protocol != ProtocolUDP {
+		t.Fatalf("Closed = %+v, want 123/udp", diff.Closed)
+	}
+	if before.Fingerprint() == after.Fingerprint() {
+		t.Fatal("reports that Diff tells apart share a fingerprint")
+	}
+}
@@ -0,0 +1,135 @@
+package portscanner
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ReportFormatter renders a report in some output format.
+type ReportFormatter interface {
+	Format(r Report, w io.Writer) error
+}
+
+// ReportFormatterFunc adapts a function to ReportFormatter.
+type ReportFormatterFunc func(r Report, w io.Writer) error
+
+func (f ReportFormatterFunc) Format(r Report, w io.Writer) error {
+	return f(r, w)
+}
+
+var (
+	// TextFormatter writes one aligned line per result, as WriteText does.
+	TextFormatter ReportFormatter = ReportFormatterFunc(formatText)
+	// JSONFormatter writes the report as a single JSON object.
+	JSONFormatter ReportFormatter = ReportFormatterFunc(formatJSON)
+	// CSVFormatter writes a header line, then one record per result.
+	CSVFormatter ReportFormatter = ReportFormatterFunc(formatCSV)
+	// PrometheusFormatter writes the report as WritePrometheus does.
+	PrometheusFormatter ReportFormatter = ReportFormatterFunc(formatPrometheus)
+)
+
+// FORMATTERS maps format names to their formatters, so the output format
+// can be chosen by name, e.g. from a command line flag. Custom formats
+// are registered by adding them.
+var FORMATTERS = map[string]ReportFormatter{
+	"text":       TextFormatter,
+	"json":       JSONFormatter,
+	"csv":        CSVFormatter,
+	"prometheus": PrometheusFormatter,
+}
+
+// WriteTo renders the report to w with f.
+func (r Report) WriteTo(w io.Writer, f ReportFormatter) error {
+	return f.Format(r, w)
+}
+
+func formatText(r Report, w io.Writer) error {
+	for _, res := range r.Results {
+		if _, err := fmt.Fprintln(w, formatTextLine(res)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type jsonResult struct {
+	Host            string   `json:"host,omitempty"`
+	Port            int      `json:"port"`
+	Protocol        string   `json:"protocol,omitempty"`
+	State           string   `json:"state"`
+	Service         string   `json:"service"`
+	Category        string   `json:"category,omitempty"`
+	Software        string   `json:"software,omitempty"`
+	CPE             string   `json:"cpe,omitempty"`
+	Unauthenticated bool     `json:"unauthenticated,omitempty"`
+	Capabilities    []string `json:"capabilities,omitempty"`
+}
+
+type jsonReport struct {
+	Host      string       `json:"host"`
+	StartedAt time.Time    `json:"started_at"`
+	Duration  float64      `json:"duration_seconds"`
+	HostDown  bool         `json:"host_down,omitempty"`
+	Aborted   string       `json:"aborted,omitempty"`
+	Results   []jsonResult `json:"results"`
+}
+
+func formatJSON(r Report, w io.Writer) error {
+	out := jsonReport{
+		Host:      r.Host,
+		StartedAt: r.StartedAt,
+		Duration:  r.Duration.Seconds(),
+		HostDown:  r.HostDown,
+		Results:   make([]jsonResult, 0, len(r.Results)),
+	}
+	if r.Aborted != nil {
+		out.Aborted = r.Aborted.Error()
+	}
+	for _, res := range r.Results {
+		out.Results = append(out.Results, newJSONResult(res))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func newJSONResult(res ScanResult) jsonResult {
+	jr := jsonResult{
+		Host:            res.Host,
+		Port:            res.Port,
+		Protocol:        res.Protocol,
+		State:           res.State.String(),
+		Service:         res.Service,
+		Category:        res.Category,
+		CPE:             res.CPE,
+		Unauthenticated: res.Unauthenticated,
+		Capabilities:    res.Capabilities,
+	}
+	if res.Software.Known() {
+		jr.Software = res.Software.String()
+	}
+	return jr
+}
+
+func formatCSV(r Report, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"host", "port", "protocol", "state", "service", "category", "software"})
+	for _, res := range r.Results {
+		software := ""
+		if res.Software.Known() {
+			software = res.Software.String()
+		}
+		cw.Write([]string{r.Host, strconv.Itoa(res.Port), res.Protocol, res.State.String(), res.Service, res.Category, software})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatPrometheus(r Report, w io.Writer) error {
+	return WritePrometheus(w, r)
+}
@@ -0,0 +1,33 @@
+package portscanner
+
+import (
+	"net"
+	"time"
+)
+
+// happyEyeballsDelay is the head start the first address family gets
+// over the other when dialing a dual-stack host, as RFC 8305 recommends.
+const happyEyeballsDelay = 250 * time.Millisecond
+
+// fallbackDelay is the net.Dialer FallbackDelay of the scanner's dials:
+// negative when WithHappyEyeballs turned the race off.
+func (ps PortScanner) fallbackDelay() time.Duration {
+	if ps.noHappyEyeballs {
+		return -1
+	}
+	return happyEyeballsDelay
+}
+
+// Family is the address family the open port was reached over, "ipv4" or
+// "ipv6", which shows which one won the race on a dual-stack host. It is
+// "" when the port is not open.
+func (r ScanResult) Family() string {
+	addr, ok := r.LocalAddr.(*net.TCPAddr)
+	switch {
+	case !ok || addr.IP == nil:
+		return ""
+	case addr.IP.To4() != nil:
+		return "ipv4"
+	}
+	return "ipv6"
+}
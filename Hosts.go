@@ -0,0 +1,167 @@
+package portscanner
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+)
+
+// maxCIDRHosts bounds the size of the ranges ScanCIDR accepts.
+const maxCIDRHosts = 1 << 16
+
+// DefaultMaxConcurrentHosts is how many hosts ScanHosts scans at once
+// unless WithMaxConcurrentHosts says otherwise.
+const DefaultMaxConcurrentHosts = 64
+
+// ScanHosts scans [start, end] on each host with the scanner's settings
+// and returns the reports keyed by host. Hosts are scanned in parallel, up
+// to WithMaxConcurrentHosts at once, each with its own threads. With
+// WithICMPPing set, hosts that do not answer are reported as HostDown and
+// not scanned. Addresses in a WithExcludedCIDR range are left out of the
+// result; host names are not resolved for that check.
+func (ps PortScanner) ScanHosts(hosts []string, start, end int) map[string]Report {
+	reports := make(map[string]Report, len(hosts))
+	var mu sync.Mutex
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, ps.hostWorkers())
+	if ps.reverseLookup {
+		ps.ptrLimiter = make(chan struct{}, maxPTRLookups)
+	}
+
+	seen := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		if seen[host] || ps.excludedHost(host) {
+			continue
+		}
+		seen[host] = true
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			report := ps.scanHost(host, start, end)
+			mu.Lock()
+			reports[host] = report
+			mu.Unlock()
+		}(host)
+	}
+
+	wg.Wait()
+	return reports
+}
+
+func (ps PortScanner) scanHost(host string, start, end int) Report {
+	target := ps
+	target.retarget(host)
+	if target.connPool != nil {
+		defer target.connPool.Close()
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		target.ip = ip
+	}
+	if ps.icmpPing && !target.hostAlive() {
+		report := Report{Host: host, HostDown: true}
+		if ps.reverseLookup {
+			report.Hostnames = target.reverseDNS()
+		}
+		return report
+	}
+	return target.Scan(start, end)
+}
+
+// hostWorkers is the most hosts ScanHosts scans at once.
+func (ps PortScanner) hostWorkers() int {
+	if ps.maxConcurrentHosts > 0 {
+		return ps.maxConcurrentHosts
+	}
+	return DefaultMaxConcurrentHosts
+}
+
+// ScanCIDR scans [start, end] on every address of cidr, such as
+// "192.168.1.0/24", and returns the reports keyed by address. The network
+// and broadcast addresses of IPv4 ranges are skipped. Ranges of more than
+// 65536 addresses are rejected.
+func (ps PortScanner) ScanCIDR(cidr string, start, end int) (map[string]Report, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("portscanner: %w", err)
+	}
+	prefix = prefix.Masked()
+
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 16 {
+		return nil, fmt.Errorf("portscanner: %s holds more than %d addresses", cidr, maxCIDRHosts)
+	}
+
+	var hosts []string
+	for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+		hosts = append(hosts, addr.String())
+	}
+	if prefix.Addr().Is4() && hostBits > 1 {
+		hosts = hosts[1 : len(hosts)-1]
+	}
+	return ps.ScanHosts(hosts, start, end), nil
+}
+
+func (ps PortScanner) excludedHost(host string) bool {
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.WithZone("").Unmap()
+	for _, prefix := range ps.excludedCIDRs {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// InterfaceFilter decides whether an address of a local interface is
+// scanned by ScanLocalInterfaces.
+type InterfaceFilter func(iface net.Interface, ip net.IP) bool
+
+// DefaultInterfaceFilter keeps addresses of interfaces that are up, except
+// link-local, multicast and unspecified ones. Loopback is kept.
+func DefaultInterfaceFilter(iface net.Interface, ip net.IP) bool {
+	if iface.Flags&net.FlagUp == 0 {
+		return false
+	}
+	return !ip.IsLinkLocalUnicast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// ScanLocalInterfaces scans the addresses of this machine's own network
+// interfaces, keyed by address, to show what it exposes on each of them.
+func (ps PortScanner) ScanLocalInterfaces(start, end int) (map[string]Report, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	filter := ps.interfaceFilter
+	if filter == nil {
+		filter = DefaultInterfaceFilter
+	}
+
+	var hosts []string
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || !filter(iface, ipNet.IP) {
+				continue
+			}
+			host := ipNet.IP.String()
+			if ipNet.IP.IsLinkLocalUnicast() && ipNet.IP.To4() == nil {
+				host += "%" + iface.Name
+			}
+			hosts = append(hosts, host)
+		}
+	}
+	return ps.ScanHosts(hosts, start, end), nil
+}
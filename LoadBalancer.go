@@ -0,0 +1,90 @@
+package portscanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Headers that change from one response to the next even when served by
+// the same backend.
+var volatileHeaders = map[string]bool{
+	"Age":             true,
+	"Cf-Ray":          true,
+	"Content-Length":  true,
+	"Date":            true,
+	"Expires":         true,
+	"Set-Cookie":      true,
+	"X-Amzn-Trace-Id": true,
+	"X-Request-Id":    true,
+	"X-Runtime":       true,
+}
+
+// countBackends opens ps.loadBalancerProbes independent connections to
+// port and returns how many distinct fingerprints they produced. web tells
+// whether the port was identified as serving HTTP.
+func (ps PortScanner) countBackends(ctx context.Context, port int, web bool) int {
+	seen := map[string]bool{}
+	for i := 0; i < ps.loadBalancerProbes; i++ {
+		if fp := ps.backendFingerprint(ctx, port, web); fp != "" {
+			seen[fp] = true
+		}
+	}
+	return len(seen)
+}
+
+// backendFingerprint summarises what a single connection to port looks
+// like: the TLS certificate and stable HTTP headers for web ports, the
+// greeting for everything else.
+func (ps PortScanner) backendFingerprint(ctx context.Context, port int, web bool) string {
+	session := ps.newSession(ps.hostPort(port))
+	session.Context = ctx
+	// Each probe needs its own connection to have a chance of reaching
+	// another backend.
+	session.Pool = nil
+	defer session.Close()
+
+	var parts []string
+	if web || ps.IsHttp(port) || ps.IsHttps(port) {
+		session.TLS = ps.IsHttps(port)
+		resp, err := session.HTTP("HEAD", "/")
+		if err != nil {
+			return ""
+		}
+		if state, ok := session.TLSState(); ok && len(state.PeerCertificates) > 0 {
+			sum := sha256.Sum256(state.PeerCertificates[0].Raw)
+			parts = append(parts, hex.EncodeToString(sum[:]))
+		}
+		parts = append(parts, stableHeaders(resp)...)
+	} else {
+		banner, err := session.Banner()
+		if err != nil || banner == "" {
+			return ""
+		}
+		parts = append(parts, banner)
+	}
+	return strings.Join(parts, "\n")
+}
+
+func stableHeaders(resp string) []string {
+	lines := strings.Split(resp, "\r\n")
+	stable := []string{lines[0]}
+	var headers []string
+	for _, line := range lines[1:] {
+		name, _, ok := strings.Cut(line, ":")
+		if !ok || volatileHeaders[http.CanonicalHeaderKey(strings.TrimSpace(name))] {
+			continue
+		}
+		headers = append(headers, line)
+	}
+	sort.Strings(headers)
+	return append(stable, headers...)
+}
+
+func loadBalancerNote(backends int) string {
+	return fmt.Sprintf("behind load balancer (%d distinct backends)", backends)
+}
@@ -0,0 +1,114 @@
+package portscanner
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds of the ScanMetrics latency
+// histogram.
+var LatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// ScanMetrics summarises the probes of a scan. Filtered counts the
+// probes that timed out; Errored those that failed for any other reason
+// than a refusal, e.g. an unreachable network.
+type ScanMetrics struct {
+	Attempted int64
+	Open      int64
+	Closed    int64
+	Filtered  int64
+	Errored   int64
+	// Latency[i] counts the probes whose dial took at most
+	// LatencyBuckets[i]; the extra last entry counts the slower ones.
+	Latency []int64
+	// RefusedLatency is Latency for the refused probes only.
+	RefusedLatency []int64
+}
+
+// metricsRecorder accumulates ScanMetrics with atomics, so probes running
+// in parallel never wait on each other. Everything recorded is also
+// added to parent, if any.
+type metricsRecorder struct {
+	parent *metricsRecorder
+
+	attempted, open, closed, filtered, errored atomic.Int64
+	latency, refusedLatency                    []atomic.Int64
+}
+
+func newMetricsRecorder(parent *metricsRecorder) *metricsRecorder {
+	return &metricsRecorder{
+		parent:         parent,
+		latency:        make([]atomic.Int64, len(LatencyBuckets)+1),
+		refusedLatency: make([]atomic.Int64, len(LatencyBuckets)+1),
+	}
+}
+
+func (m *metricsRecorder) record(latency time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.attempted.Add(1)
+	switch {
+	case err == nil:
+		m.open.Add(1)
+	case classifyDialError(err) == PortClosed:
+		m.closed.Add(1)
+	case isTimeout(err):
+		m.filtered.Add(1)
+	default:
+		m.errored.Add(1)
+	}
+
+	bucket := len(LatencyBuckets)
+	for i, bound := range LatencyBuckets {
+		if latency <= bound {
+			bucket = i
+			break
+		}
+	}
+	m.latency[bucket].Add(1)
+	if err != nil && classifyDialError(err) == PortClosed {
+		m.refusedLatency[bucket].Add(1)
+	}
+
+	m.parent.record(latency, err)
+}
+
+func (m *metricsRecorder) snapshot() ScanMetrics {
+	if m == nil {
+		return ScanMetrics{Latency: make([]int64, len(LatencyBuckets)+1), RefusedLatency: make([]int64, len(LatencyBuckets)+1)}
+	}
+	metrics := ScanMetrics{
+		Attempted: m.attempted.Load(),
+		Open:      m.open.Load(),
+		Closed:    m.closed.Load(),
+		Filtered:  m.filtered.Load(),
+		Errored:   m.errored.Load(),
+		Latency:   make([]int64, len(m.latency)),
+
+		RefusedLatency: make([]int64, len(m.refusedLatency)),
+	}
+	for i := range m.latency {
+		metrics.Latency[i] = m.latency[i].Load()
+		metrics.RefusedLatency[i] = m.refusedLatency[i].Load()
+	}
+	return metrics
+}
+
+// Metrics returns the totals of every probe the scanner has made since it
+// was created, across all scans and its copies.
+func (ps PortScanner) Metrics() ScanMetrics {
+	return ps.metrics.snapshot()
+}
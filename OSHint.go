@@ -0,0 +1,79 @@
+package portscanner
+
+import "strings"
+
+// OSIndicator names the operating system a piece of banner text betrays,
+// such as "Ubuntu" in an SSH banner or "Win64" in a Server header.
+// Matching ignores case.
+type OSIndicator struct {
+	Text string
+	OS   string
+}
+
+// OS_INDICATORS are looked for in the service descriptions and software
+// of a report by GuessOS. Add to it to recognise other systems.
+var OS_INDICATORS = []OSIndicator{
+	{"Ubuntu", "Ubuntu Linux"},
+	{"Debian", "Debian Linux"},
+	{"Raspbian", "Raspbian Linux"},
+	{"CentOS", "CentOS Linux"},
+	{"Red Hat", "Red Hat Enterprise Linux"},
+	{"RHEL", "Red Hat Enterprise Linux"},
+	{"Fedora", "Fedora Linux"},
+	{"Alpine", "Alpine Linux"},
+	{"FreeBSD", "FreeBSD"},
+	{"OpenBSD", "OpenBSD"},
+	{"NetBSD", "NetBSD"},
+	{"Win32", "Windows"},
+	{"Win64", "Windows"},
+	{"Windows", "Windows"},
+	{"Microsoft-IIS", "Windows"},
+	{"Darwin", "macOS"},
+}
+
+// OSGuess is the operating system a host most likely runs, inferred from
+// what its services disclose. It is a heuristic: Confidence, from 0 to 1,
+// grows with the number of ports agreeing on it and drops when others
+// point elsewhere. Ports lists the ports that pointed to it.
+type OSGuess struct {
+	OS         string
+	Confidence float64
+	Ports      []int
+}
+
+// GuessOS looks for OS_INDICATORS in the results and returns the system
+// most of them point to. The zero OSGuess means nothing was disclosed.
+func GuessOS(results []ScanResult) OSGuess {
+	votes := map[string][]int{}
+	total := 0
+	for _, res := range results {
+		if os := indicatedOS(res.Service + " " + res.Software.OS); os != "" {
+			votes[os] = append(votes[os], res.Port)
+			total++
+		}
+	}
+
+	var guess OSGuess
+	for os, ports := range votes {
+		if len(ports) > len(guess.Ports) || (len(ports) == len(guess.Ports) && os < guess.OS) {
+			guess = OSGuess{OS: os, Ports: ports}
+		}
+	}
+	if guess.OS != "" {
+		// A single hit is an even bet; every port in agreement firms it
+		// up, every port in disagreement weakens it.
+		guess.Confidence = float64(len(guess.Ports)) / float64(total+1)
+	}
+	return guess
+}
+
+// indicatedOS returns the OS of the first indicator found in text.
+func indicatedOS(text string) string {
+	text = strings.ToLower(text)
+	for _, indicator := range OS_INDICATORS {
+		if strings.Contains(text, strings.ToLower(indicator.Text)) {
+			return indicator.OS
+		}
+	}
+	return ""
+}
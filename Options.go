@@ -0,0 +1,690 @@
+package portscanner
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// Option configures a PortScanner. Options are applied with Apply and
+// report invalid values as errors.
+type Option func(*PortScanner) error
+
+// Apply applies opts in order and stops at the first one that fails.
+func (ps *PortScanner) Apply(opts ...Option) error {
+	for _, opt := range opts {
+		if err := opt(ps); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithScanAllAddresses makes ScanAddresses scan every A/AAAA record of the
+// host instead of letting the dialer pick one.
+func WithScanAllAddresses(all bool) Option {
+	return func(ps *PortScanner) error {
+		ps.scanAllAddresses = all
+		return nil
+	}
+}
+
+// WithLogger sets the logger used to report problems that do not stop a
+// scan, such as a panicking predictor. Every port described is also
+// logged at Info level, with the step that identified it, the start of
+// what the service sent and the final label, as an audit trail of the
+// findings. Nothing is logged by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(ps *PortScanner) error {
+		ps.logger = logger
+		return nil
+	}
+}
+
+// WithTLSConfig sets the configuration used by predictors that speak TLS,
+// e.g. to pick the SNI server name or present a client certificate. By
+// default certificates are not verified and ServerName is the host.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(ps *PortScanner) error {
+		ps.tlsConfig = cfg
+		return nil
+	}
+}
+
+// WithDescribeBackpressure makes DescribePorts adapt its concurrency to the
+// target: while the moving average of describe times is above slow, one
+// worker is removed after each description, and while it is below
+// recovered, one is added back up to the thread count.
+func WithDescribeBackpressure(slow, recovered time.Duration) Option {
+	return func(ps *PortScanner) error {
+		if slow <= 0 || recovered <= 0 || recovered >= slow {
+			return errors.New("portscanner: backpressure thresholds must satisfy 0 < recovered < slow")
+		}
+		ps.describeSlow = slow
+		ps.describeRecovered = recovered
+		return nil
+	}
+}
+
+// WithNetwork restricts every dial to "tcp4" or "tcp6". The default, "tcp",
+// uses whichever family the host resolves to.
+func WithNetwork(network string) Option {
+	return func(ps *PortScanner) error {
+		switch network {
+		case "tcp", "tcp4", "tcp6":
+			ps.network = network
+			return nil
+		}
+		return fmt.Errorf("portscanner: unsupported network %q", network)
+	}
+}
+
+// WithInterfaceFilter replaces DefaultInterfaceFilter in
+// ScanLocalInterfaces.
+func WithInterfaceFilter(filter InterfaceFilter) Option {
+	return func(ps *PortScanner) error {
+		ps.interfaceFilter = filter
+		return nil
+	}
+}
+
+// WithMaxResponseBytes caps how many bytes any predictor reads from a
+// single service response. It defaults to predictors.DefaultMaxResponseBytes.
+func WithMaxResponseBytes(n int) Option {
+	return func(ps *PortScanner) error {
+		if n <= 0 {
+			return fmt.Errorf("portscanner: max response bytes must be positive, got %d", n)
+		}
+		ps.maxResponseBytes = n
+		return nil
+	}
+}
+
+// WithFastClose makes IsOpen reset probe connections (SO_LINGER 0) rather
+// than closing them gracefully. This avoids exhausting local ports with
+// sockets in TIME_WAIT during large scans, at the cost of sending the
+// target an RST instead of a FIN.
+func WithFastClose(fastClose bool) Option {
+	return func(ps *PortScanner) error {
+		ps.fastClose = fastClose
+		return nil
+	}
+}
+
+// WithSystemServices makes ports missing from KNOWN_PORTS fall back to the
+// names in /etc/services. KNOWN_PORTS still wins when both know a port.
+// Such ports are probed as before; their name is what DescribePort falls
+// back to when the predictors find nothing, as a KNOWN_PORTS label is.
+func WithSystemServices(use bool) Option {
+	return func(ps *PortScanner) error {
+		ps.useSystemServices = use
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests sent by HTTP
+// predictors. It defaults to predictors.DefaultUserAgent.
+func WithUserAgent(ua string) Option {
+	return func(ps *PortScanner) error {
+		if strings.ContainsAny(ua, "\r\n") {
+			return errors.New("portscanner: user agent must not contain line breaks")
+		}
+		ps.userAgent = ua
+		return nil
+	}
+}
+
+// WithHTTPAuth sets the Authorization header sent by HTTP predictors, e.g.
+// "Basic dXNlcjpwYXNz" or "Bearer <token>", so services that answer a bare
+// 401 to anonymous clients can be fingerprinted. The value is never
+// logged; an error about it does not repeat it.
+func WithHTTPAuth(header string) Option {
+	return func(ps *PortScanner) error {
+		if strings.ContainsAny(header, "\r\n") {
+			return errors.New("portscanner: authorization header must not contain line breaks")
+		}
+		ps.httpAuth = header
+		return nil
+	}
+}
+
+// WithLoadBalancerDetection makes DescribePort open probes extra
+// connections to each open port and compare what they see (certificate,
+// headers or greeting). When they differ the description notes that the
+// service is behind a load balancer. Zero, the default, disables it.
+func WithLoadBalancerDetection(probes int) Option {
+	return func(ps *PortScanner) error {
+		if probes < 0 || probes == 1 {
+			return fmt.Errorf("portscanner: load balancer detection needs 0 or at least 2 probes, got %d", probes)
+		}
+		ps.loadBalancerProbes = probes
+		return nil
+	}
+}
+
+// WithDescendingOrder makes range scans dispatch ports from end down to
+// start. Results are the same; only the order of probes on the wire
+// changes.
+func WithDescendingOrder(descending bool) Option {
+	return func(ps *PortScanner) error {
+		ps.descending = descending
+		return nil
+	}
+}
+
+// ConnectCallback observes a single dial made to test whether a port is
+// open.
+type ConnectCallback func(port int, duration time.Duration, err error)
+
+// WithConnectCallback registers fn to be called once after every dial made
+// by IsOpen and the scans built on it, whatever its outcome. fn may be
+// called from several goroutines at once.
+func WithConnectCallback(fn ConnectCallback) Option {
+	return func(ps *PortScanner) error {
+		ps.onConnect = fn
+		return nil
+	}
+}
+
+// WithTreatErrorsAsOpen reports a port as open when its dial fails with an
+// error accepted by any of matchers. It is meant for environments where a
+// middlebox answers in unusual ways for reachable services; other errors
+// are handled as usual.
+func WithTreatErrorsAsOpen(matchers []func(error) bool) Option {
+	return func(ps *PortScanner) error {
+		for _, match := range matchers {
+			if match == nil {
+				return errors.New("portscanner: nil error matcher")
+			}
+		}
+		ps.openErrors = append([]func(error) bool(nil), matchers...)
+		return nil
+	}
+}
+
+// WithDescribeCache keeps descriptions of host:port pairs for ttl, so
+// describing the same open port again does not re-run the predictors.
+// SetHost drops the cache. A zero ttl disables it.
+func WithDescribeCache(ttl time.Duration) Option {
+	return func(ps *PortScanner) error {
+		if ttl < 0 {
+			return fmt.Errorf("portscanner: negative describe cache ttl %s", ttl)
+		}
+		if ttl == 0 {
+			ps.describeCache = nil
+			return nil
+		}
+		ps.describeCache = newDescribeCache(ttl)
+		return nil
+	}
+}
+
+// WithKnownPortsFirst makes range scans dispatch the ports listed in
+// KNOWN_PORTS before the rest of the range, so high-value services are
+// found early in long scans.
+func WithKnownPortsFirst(first bool) Option {
+	return func(ps *PortScanner) error {
+		ps.knownPortsFirst = first
+		return nil
+	}
+}
+
+// WithICMPPing makes ScanHosts send an ICMP echo to each host first and
+// skip those that do not answer within the timeout. Raw ICMP needs
+// privileges; without them an unprivileged ping socket is tried, and
+// failing that a TCP connect to a few common ports is used instead.
+func WithICMPPing(ping bool) Option {
+	return func(ps *PortScanner) error {
+		ps.icmpPing = ping
+		return nil
+	}
+}
+
+// WithResetBackoff lowers scan concurrency while the target answers with a
+// burst of RSTs, as rate-limiting hosts do. sensitivity, between 0 and 1,
+// is how far the share of refused or reset probes in a window may rise
+// above its running average before backing off; lower values react
+// sooner. Backoff changes are logged through WithLogger.
+func WithResetBackoff(sensitivity float64) Option {
+	return func(ps *PortScanner) error {
+		if sensitivity <= 0 || sensitivity >= 1 {
+			return fmt.Errorf("portscanner: reset backoff sensitivity must be in (0, 1), got %v", sensitivity)
+		}
+		ps.resetSensitivity = sensitivity
+		return nil
+	}
+}
+
+// WithSecondPass makes Scan retry the ports that timed out, once the
+// whole range has been probed, with the longer timeout and a quarter of
+// the threads. Ports found open on the retry are listed in
+// Report.SecondPass. Zero disables the second pass.
+func WithSecondPass(longerTimeout time.Duration) Option {
+	return func(ps *PortScanner) error {
+		if longerTimeout < 0 {
+			return fmt.Errorf("portscanner: negative second pass timeout %s", longerTimeout)
+		}
+		if longerTimeout > 0 && longerTimeout <= ps.timeout {
+			return fmt.Errorf("portscanner: second pass timeout %s is not longer than the scan timeout %s", longerTimeout, ps.timeout)
+		}
+		ps.secondPassTimeout = longerTimeout
+		return nil
+	}
+}
+
+// WithScanTimeout bounds the time spent describing one open port, all
+// predictors included. When it runs out, reads in progress are cut short
+// and the predictors left are skipped, so a service that drips its answer
+// byte by byte cannot stall the scan. Zero, the default, leaves only the
+// per-connection timeout.
+func WithScanTimeout(d time.Duration) Option {
+	return func(ps *PortScanner) error {
+		if d < 0 {
+			return fmt.Errorf("portscanner: negative scan timeout %s", d)
+		}
+		ps.describeTimeout = d
+		return nil
+	}
+}
+
+// filteredTimeoutDivisor is how much shorter the dial timeout is for the
+// ports set by WithSkipFiltered.
+const filteredTimeoutDivisor = 4
+
+// WithSkipFiltered probes the ports previous found filtered with a quarter
+// of the timeout, so repeat scans of the same host do not wait in full on
+// ports that did not answer last time. It trades a little accuracy for
+// speed: a port that opened since, behind a slow path, may be reported
+// filtered again. previous must be a report of the scanner's host;
+// SetHost forgets it.
+func WithSkipFiltered(previous Report) Option {
+	return func(ps *PortScanner) error {
+		if previous.Host != ps.host {
+			return fmt.Errorf("portscanner: report is for host %q, not %q", previous.Host, ps.host)
+		}
+		ps.knownFiltered = make(map[int]bool, len(previous.Filtered))
+		for _, port := range previous.Filtered {
+			ps.knownFiltered[port] = true
+		}
+		return nil
+	}
+}
+
+// WithProbe registers a custom TCP probe for port: payload is sent on a
+// fresh connection (nothing is sent when it is empty), everything the
+// service answers before closing or timing out is read, and match turns
+// it into a description, or "" when it does not recognise it. Probes run
+// before the predictors, in the order they were registered, and the
+// first description wins.
+func WithProbe(port int, payload []byte, match func([]byte) string) Option {
+	return func(ps *PortScanner) error {
+		if port < MinPort || port > MaxPort {
+			return fmt.Errorf("portscanner: probe port %d out of range", port)
+		}
+		if match == nil {
+			return errors.New("portscanner: probe needs a matcher")
+		}
+		probes := make(map[int][]customProbe, len(ps.probes)+1)
+		for p, registered := range ps.probes {
+			probes[p] = registered
+		}
+		probe := customProbe{payload: append([]byte(nil), payload...), match: match}
+		probes[port] = append(append([]customProbe(nil), probes[port]...), probe)
+		ps.probes = probes
+		return nil
+	}
+}
+
+// WithResolver resolves the host, for scanning and describing alike,
+// through r instead of net.DefaultResolver: an internal DNS server for
+// split-horizon names, or a custom transport set through r.Dial.
+func WithResolver(r *net.Resolver) Option {
+	return func(ps *PortScanner) error {
+		if r == nil {
+			return errors.New("portscanner: nil resolver")
+		}
+		ps.dnsResolver = r
+		return nil
+	}
+}
+
+// WithExcludedCIDR keeps ScanHosts, and so ScanCIDR and
+// ScanLocalInterfaces, away from every address in the given ranges, e.g.
+// gateways or monitoring hosts. Each call adds to the ranges already
+// excluded.
+func WithExcludedCIDR(cidrs ...string) Option {
+	return func(ps *PortScanner) error {
+		excluded := append([]netip.Prefix(nil), ps.excludedCIDRs...)
+		for _, cidr := range cidrs {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				return fmt.Errorf("portscanner: excluded range: %w", err)
+			}
+			excluded = append(excluded, prefix.Masked())
+		}
+		ps.excludedCIDRs = excluded
+		return nil
+	}
+}
+
+// WithDescribeConcurrency caps how many open ports are described at
+// once, independently of the threads probing ports. Zero, the default,
+// uses the thread count.
+func WithDescribeConcurrency(n int) Option {
+	return func(ps *PortScanner) error {
+		if n < 0 {
+			return fmt.Errorf("portscanner: negative describe concurrency %d", n)
+		}
+		ps.describeConcurrency = n
+		return nil
+	}
+}
+
+// WithTCPFastOpen makes the HTTP connections of describe probes use TCP
+// Fast Open, so repeat scans of the same host save a round trip per
+// connection. It is only available on Linux, where the kernel must allow
+// client use (the 0x1 bit of net.ipv4.tcp_fastopen, on by default), and
+// only helps once a first connection has obtained a cookie. Port probes
+// never use it: a Fast Open connect succeeds before the server has
+// answered, which would make every port look open.
+func WithTCPFastOpen(enable bool) Option {
+	return func(ps *PortScanner) error {
+		if enable && !predictors.FastOpenSupported {
+			return errors.New("portscanner: TCP Fast Open is not supported on this platform")
+		}
+		ps.tcpFastOpen = enable
+		return nil
+	}
+}
+
+// WithStartupJitter delays each of the first threads probes of a scan by
+// a random duration up to max, so the connection rate ramps up instead of
+// every worker dialing at the same instant. Zero, the default, dials at
+// once.
+func WithStartupJitter(max time.Duration) Option {
+	return func(ps *PortScanner) error {
+		if max < 0 {
+			return fmt.Errorf("portscanner: negative startup jitter %s", max)
+		}
+		ps.startupJitter = max
+		return nil
+	}
+}
+
+// WithConnectTimeout sets how long a dial may take, the timeout given to
+// NewPortScanner. It alone decides whether a port is open or filtered.
+func WithConnectTimeout(d time.Duration) Option {
+	return func(ps *PortScanner) error {
+		if d <= 0 {
+			return fmt.Errorf("portscanner: connect timeout must be positive, got %s", d)
+		}
+		ps.timeout = d
+		return nil
+	}
+}
+
+// WithReadTimeout sets how long the predictors and the banner reads wait
+// on each exchange with an open port, independently of the connect
+// timeout. Services that take a while to greet may need more than a dial
+// does. It applies to every protocol, PROTOCOL_TIMEOUTS included. Zero,
+// the default, uses the connect timeout, lengthened for the protocols of
+// PROTOCOL_TIMEOUTS.
+func WithReadTimeout(d time.Duration) Option {
+	return func(ps *PortScanner) error {
+		if d < 0 {
+			return fmt.Errorf("portscanner: negative read timeout %s", d)
+		}
+		ps.readTimeout = d
+		return nil
+	}
+}
+
+// WithMaxConcurrentHosts bounds how many hosts ScanHosts, ScanCIDR and
+// ScanLocalInterfaces scan at once, DefaultMaxConcurrentHosts by default.
+// Each host still gets the scanner's threads, so up to n times threads
+// dials may be in flight.
+func WithMaxConcurrentHosts(n int) Option {
+	return func(ps *PortScanner) error {
+		if n < 1 {
+			return fmt.Errorf("portscanner: max concurrent hosts must be at least 1, got %d", n)
+		}
+		ps.maxConcurrentHosts = n
+		return nil
+	}
+}
+
+// WithReverseDNS looks up the PTR names of each scanned address and
+// reports them as Report.Hostnames, turning the results of a CIDR sweep
+// into a readable inventory. Addresses without a PTR record simply have
+// none. Off by default.
+func WithReverseDNS(enable bool) Option {
+	return func(ps *PortScanner) error {
+		ps.reverseLookup = enable
+		return nil
+	}
+}
+
+// WithCircuitBreaker tunes the breaker that pauses dials for pause once
+// threshold dials in a row failed because this machine ran out of file
+// descriptors or local ports, as happens on very large scans. The ports
+// caught by it are probed again after the pause rather than reported as
+// errors. Defaults are DefaultBreakerThreshold and DefaultBreakerPause.
+func WithCircuitBreaker(threshold int, pause time.Duration) Option {
+	return func(ps *PortScanner) error {
+		if threshold < 1 {
+			return fmt.Errorf("portscanner: circuit breaker threshold must be at least 1, got %d", threshold)
+		}
+		if pause <= 0 {
+			return fmt.Errorf("portscanner: circuit breaker pause must be positive, got %s", pause)
+		}
+		ps.breakerThreshold, ps.breakerPause = threshold, pause
+		return nil
+	}
+}
+
+// WithSourceAddress makes every dial, probes and predictors alike, leave
+// from ip, which must be an address of this machine. It picks the path of
+// a multi-homed scanner.
+func WithSourceAddress(ip net.IP) Option {
+	return func(ps *PortScanner) error {
+		if ip == nil || ip.IsUnspecified() {
+			return fmt.Errorf("portscanner: invalid source address %v", ip)
+		}
+		ps.sourceIP = ip
+		return nil
+	}
+}
+
+// WithInterface is WithSourceAddress with the address of the named
+// interface, e.g. "eth0" or "tun0". The interface must be up and have a
+// unicast address that is not link-local; an IPv4 one is preferred unless
+// WithNetwork("tcp6") came first.
+func WithInterface(name string) Option {
+	return func(ps *PortScanner) error {
+		ip, err := interfaceAddr(name, ps.network)
+		if err != nil {
+			return err
+		}
+		ps.sourceIP = ip
+		return nil
+	}
+}
+
+// WithSocketOptions calls control on every socket the scanner opens,
+// probes and predictors alike, after it is created and before it
+// connects, as net.Dialer.Control does. It is the place to set options
+// the package has no setting for, such as SO_RCVBUF, IP_TOS or TCP_MAXSEG,
+// with the constants of the target platform. An error aborts the dial.
+func WithSocketOptions(control func(network, address string, c syscall.RawConn) error) Option {
+	return func(ps *PortScanner) error {
+		ps.socketOptions = control
+		return nil
+	}
+}
+
+// WithAdaptiveGreeting bounds the wait for the greeting of services that
+// speak first, SSH, SMTP or MySQL among them, by base plus a few times the
+// time the connection took to set up, and never more than the read
+// timeout. On a fast network the describe step no longer idles for the
+// whole read timeout on ports that stay silent. Servers that delay their
+// greeting on purpose, as some mail servers do against spam, may be
+// missed with a small base. Zero, the default, waits the read timeout.
+func WithAdaptiveGreeting(base time.Duration) Option {
+	return func(ps *PortScanner) error {
+		if base < 0 {
+			return fmt.Errorf("portscanner: negative greeting base %s", base)
+		}
+		ps.greetingBase = base
+		return nil
+	}
+}
+
+// WithAutoConcurrency replaces the fixed thread count of port probes with
+// one adapting to the target and the network: it starts at min, ramps up
+// to max while answers come back fast, and halves when they slow down or
+// fail, as TCP congestion control does. Describing still uses max
+// workers, unless WithDescribeConcurrency says otherwise.
+func WithAutoConcurrency(min, max int) Option {
+	return func(ps *PortScanner) error {
+		if min < 1 || max < min {
+			return fmt.Errorf("portscanner: invalid auto concurrency range [%d, %d]", min, max)
+		}
+		ps.autoMin, ps.threads = min, max
+		return nil
+	}
+}
+
+// WithPortFilter restricts every scan to the ports filter accepts, on top
+// of the ports or range asked for: the others are never dialed, over TCP
+// or UDP. It is consulted once per port, from a single goroutine, after
+// the excludes of a Config have already removed their ports.
+func WithPortFilter(filter func(port int) bool) Option {
+	return func(ps *PortScanner) error {
+		ps.portFilter = filter
+		return nil
+	}
+}
+
+// WithDescribeChain replaces DefaultDescribeChain, the steps open ports
+// are identified by, in order. The first step to find the service wins;
+// ports no step identifies are described as UNKNOWN. For instance, to
+// trust port numbers before any other detection:
+//
+//	portscanner.WithDescribeChain(portscanner.DescribeKnownPorts, portscanner.DescribeActive)
+func WithDescribeChain(steps ...DescribeStep) Option {
+	return func(ps *PortScanner) error {
+		if len(steps) == 0 {
+			return fmt.Errorf("portscanner: empty describe chain")
+		}
+		ps.describeSteps = append([]DescribeStep(nil), steps...)
+		return nil
+	}
+}
+
+// WithOpenCache keeps the outcome of IsOpen, IsOpenE and State for ttl
+// per host:port, so checking the same port several ways in a row dials it
+// once. SetHost and SetTimeout drop the cache. Scans always dial. Leave it
+// off when ports are watched for changes, as a cached answer may be stale
+// for up to ttl. A zero ttl disables it.
+func WithOpenCache(ttl time.Duration) Option {
+	return func(ps *PortScanner) error {
+		if ttl < 0 {
+			return fmt.Errorf("portscanner: negative open cache ttl %s", ttl)
+		}
+		if ttl == 0 {
+			ps.openCache = nil
+			return nil
+		}
+		ps.openCache = newTTLCache[openResult](ttl)
+		return nil
+	}
+}
+
+// WithHappyEyeballs races IPv4 and IPv6 when a host name resolves to
+// both, giving the preferred family a short head start and using the
+// first connection to succeed, as RFC 8305 describes. A service that only
+// listens on one family is then found whichever the resolver lists
+// first; ScanResult.Family tells which one answered. It only applies to
+// the "tcp" network, and is on by default.
+func WithHappyEyeballs(enabled bool) Option {
+	return func(ps *PortScanner) error {
+		ps.noHappyEyeballs = !enabled
+		return nil
+	}
+}
+
+// WithSourcePorts binds every dial to a local port of [low, high], taken
+// in turn, instead of an ephemeral one, for stateful filters that only let
+// some source ports through. low == high pins a single port.
+//
+// On Linux, dials set SO_REUSEADDR, so several of them can share a port
+// as long as their destinations differ. Dialing the same host:port again
+// from a port whose earlier connection is still in TIME_WAIT fails, and
+// the dial is retried from the next port, but a range of a single port
+// leaves nothing to retry from. Elsewhere a port only takes one dial at a
+// time: give the range at least as many ports as threads. Ports below
+// 1024 usually need privileges.
+func WithSourcePorts(low, high int) Option {
+	return func(ps *PortScanner) error {
+		if low < MinPort || high > MaxPort || high < low {
+			return fmt.Errorf("portscanner: invalid source port range %d-%d", low, high)
+		}
+		ps.sourcePorts = &portRotor{low: low, high: high}
+		return nil
+	}
+}
+
+// WithResultSink makes scans write their results to sink, with Host set,
+// as each port is done, rather than keep them: the Results of the reports
+// are left empty, while the rest of each report is filled as usual. This
+// keeps memory flat over scans of many hosts. Writes are serialized, even
+// across the hosts ScanHosts scans in parallel. Scans never close the
+// sink; the caller does once done with it.
+func WithResultSink(sink ResultSink) Option {
+	return func(ps *PortScanner) error {
+		if sink == nil {
+			ps.resultSink = nil
+			return nil
+		}
+		ps.resultSink = &lockedSink{sink: sink}
+		return nil
+	}
+}
+
+// WithVerbose makes WriteText list closed and filtered ports as well as
+// open ones.
+func WithVerbose(verbose bool) Option {
+	return func(ps *PortScanner) error {
+		ps.verbose = verbose
+		return nil
+	}
+}
+
+// WithConnPoolPerHost keeps up to n idle HTTP keep-alive connections to
+// the scanned host, so describe probes of the same port made by later
+// calls skip the TCP and TLS handshakes. Protocols that cannot reuse a
+// connection keep dialing fresh ones. SetHost empties the pool. Zero
+// disables pooling.
+func WithConnPoolPerHost(n int) Option {
+	return func(ps *PortScanner) error {
+		if n < 0 {
+			return fmt.Errorf("portscanner: negative connection pool size %d", n)
+		}
+		if ps.connPool != nil {
+			ps.connPool.Close()
+		}
+		ps.connPool, ps.connPoolSize = nil, n
+		if n > 0 {
+			ps.connPool = predictors.NewConnPool(n)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,126 @@
+package portscanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// Ports dialed to confirm a host is up when ICMP cannot be used.
+var livenessPorts = []int{80, 443, 22}
+
+// hostAlive reports whether the host answers an ICMP echo within the
+// scanner timeout. When the process may not send ICMP, it falls back to
+// TCP: any answer on a common port, even a refusal, proves the host is up.
+func (ps PortScanner) hostAlive() bool {
+	alive, err := ps.icmpEcho()
+	if err == nil {
+		return alive
+	}
+	if ps.logger != nil {
+		ps.logger.Debug("icmp ping unavailable, using tcp liveness", "host", ps.host, "err", err)
+	}
+	return ps.tcpAlive()
+}
+
+func (ps PortScanner) icmpEcho() (bool, error) {
+	ip := ps.ip
+	if ip == nil {
+		addrs, err := ps.resolver().LookupIP(context.Background(), ipNetwork(ps.network), ps.host)
+		if err != nil || len(addrs) == 0 {
+			return false, err
+		}
+		ip = addrs[0]
+	}
+
+	v4 := ip.To4() != nil
+	conn, privileged, err := listenICMP(v4)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	var msgType icmp.Type = ipv4.ICMPTypeEcho
+	proto := 1
+	if !v4 {
+		msgType, proto = ipv6.ICMPTypeEchoRequest, 58
+	}
+	msg := icmp.Message{
+		Type: msgType,
+		Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: 1, Data: []byte("port-scanner")},
+	}
+	payload, err := msg.Marshal(nil)
+	if err != nil {
+		return false, err
+	}
+
+	var dst net.Addr = &net.IPAddr{IP: ip}
+	if !privileged {
+		dst = &net.UDPAddr{IP: ip}
+	}
+	if _, err := conn.WriteTo(payload, dst); err != nil {
+		return false, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(ps.timeout))
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			// A timeout is an answer: the host did not reply.
+			return false, nil
+		}
+		reply, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil {
+			continue
+		}
+		if reply.Type != ipv4.ICMPTypeEchoReply && reply.Type != ipv6.ICMPTypeEchoReply {
+			continue
+		}
+		if peerIP(peer).Equal(ip) {
+			return true, nil
+		}
+	}
+}
+
+// listenICMP opens a raw ICMP socket, or an unprivileged datagram one
+// where the system allows it.
+func listenICMP(v4 bool) (*icmp.PacketConn, bool, error) {
+	network, address := "ip4:icmp", "0.0.0.0"
+	udpNetwork := "udp4"
+	if !v4 {
+		network, address, udpNetwork = "ip6:ipv6-icmp", "::", "udp6"
+	}
+	if conn, err := icmp.ListenPacket(network, address); err == nil {
+		return conn, true, nil
+	}
+	conn, err := icmp.ListenPacket(udpNetwork, address)
+	return conn, false, err
+}
+
+func peerIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	}
+	return nil
+}
+
+func (ps PortScanner) tcpAlive() bool {
+	for _, port := range livenessPorts {
+		_, err := ps.IsOpenE(port)
+		if err == nil || errors.Is(err, syscall.ECONNREFUSED) {
+			return true
+		}
+	}
+	return false
+}
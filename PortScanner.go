@@ -1,45 +1,208 @@
 package portscanner
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math/rand/v2"
 	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/elchemista/port-scanner/predictors"
+	"github.com/elchemista/port-scanner/predictors/ci"
+	"github.com/elchemista/port-scanner/predictors/docker"
+	"github.com/elchemista/port-scanner/predictors/git"
+	"github.com/elchemista/port-scanner/predictors/grpc"
+	"github.com/elchemista/port-scanner/predictors/influxdb"
+	"github.com/elchemista/port-scanner/predictors/irc"
+	"github.com/elchemista/port-scanner/predictors/kafka"
+	"github.com/elchemista/port-scanner/predictors/ldap"
+	"github.com/elchemista/port-scanner/predictors/mail"
+	"github.com/elchemista/port-scanner/predictors/mongodb"
+	"github.com/elchemista/port-scanner/predictors/mqtt"
+	"github.com/elchemista/port-scanner/predictors/ntp"
+	"github.com/elchemista/port-scanner/predictors/postgres"
+	"github.com/elchemista/port-scanner/predictors/prometheus"
+	"github.com/elchemista/port-scanner/predictors/redis"
+	"github.com/elchemista/port-scanner/predictors/rtsp"
+	"github.com/elchemista/port-scanner/predictors/socks"
 	"github.com/elchemista/port-scanner/predictors/webserver"
+	"github.com/elchemista/port-scanner/predictors/zookeeper"
 )
 
 const UNKNOWN = "<unknown>"
 
 type PortScanner struct {
 	host         string
+	ip           net.IP
 	predictors   []predictors.Predictor
 	timeout      time.Duration
 	threads      int
 	usePredictor bool
+	network      string
+
+	scanAllAddresses bool
+	logger           *slog.Logger
+	tlsConfig        *tls.Config
+	interfaceFilter  InterfaceFilter
+	maxResponseBytes int
+	fastClose        bool
+	userAgent        string
+
+	loadBalancerProbes int
+	descending         bool
+	knownPortsFirst    bool
+	onConnect          ConnectCallback
+	openErrors         []func(error) bool
+	describeCache      *describeCache
+	icmpPing           bool
+	resetSensitivity   float64
+	verbose            bool
+	connPool           *predictors.ConnPool
+	connPoolSize       int
+	metrics            *metricsRecorder
+	secondPassTimeout  time.Duration
+	httpAuth           string
+	describeTimeout    time.Duration
+	knownFiltered      map[int]bool
+	probes             map[int][]customProbe
+	dnsResolver        *net.Resolver
+	excludedCIDRs      []netip.Prefix
+
+	describeConcurrency int
+	tcpFastOpen         bool
+	startupJitter       time.Duration
+	readTimeout         time.Duration
+	maxConcurrentHosts  int
+	reverseLookup       bool
+	ptrLimiter          chan struct{}
+	breakerThreshold    int
+	breakerPause        time.Duration
+	sourceIP            net.IP
+	socketOptions       func(network, address string, c syscall.RawConn) error
+	greetingBase        time.Duration
+	autoMin             int
+	portFilter          func(port int) bool
+	describeSteps       []DescribeStep
+	openCache           *ttlCache[openResult]
+	noHappyEyeballs     bool
+	sourcePorts         *portRotor
+	resultSink          ResultSink
+
+	useSystemServices bool
+
+	describeSlow      time.Duration
+	describeRecovered time.Duration
 }
 
 func NewPortScanner(host string, timeout time.Duration, threads int) *PortScanner {
 	return &PortScanner{
-		host:         host,
-		predictors:   []predictors.Predictor{&webserver.ApachePredictor{}, &webserver.NginxPredictor{}},
+		host: host,
+		predictors: []predictors.Predictor{
+			// Before the web servers, which would otherwise claim the
+			// consoles they serve.
+			&ci.CIPredictor{},
+			&webserver.ApachePredictor{},
+			&webserver.NginxPredictor{},
+			&ldap.LDAPPredictor{},
+			&socks.SocksPredictor{},
+			&git.GitPredictor{},
+			&irc.IRCPredictor{},
+			&zookeeper.ZookeeperPredictor{},
+			&redis.RedisPredictor{},
+			&mongodb.MongoDBPredictor{},
+			&docker.DockerPredictor{},
+			&influxdb.InfluxDBPredictor{},
+			&grpc.GRPCPredictor{},
+			&mail.SMTPPredictor{},
+			&mail.IMAPPredictor{},
+			&mail.POP3Predictor{},
+			&ntp.NTPPredictor{},
+			&prometheus.PrometheusPredictor{},
+			&kafka.KafkaPredictor{},
+			&rtsp.RTSPPredictor{},
+			&postgres.PostgresPredictor{},
+			&mqtt.MQTTPredictor{},
+		},
 		timeout:      timeout,
 		threads:      threads,
 		usePredictor: true,
+		network:      "tcp",
+		metrics:      newMetricsRecorder(nil),
 	}
 }
 
+// NewPortScannerIP builds a scanner for a literal address. Dials target
+// the IP directly, so no name resolution ever takes place.
+func NewPortScannerIP(ip net.IP, timeout time.Duration, threads int) *PortScanner {
+	ps := NewPortScanner(ip.String(), timeout, threads)
+	ps.ip = ip
+	return ps
+}
+
 func (ps *PortScanner) TogglePredictor(usePredictor bool) {
 	ps.usePredictor = usePredictor
 }
 
+// SetHost points the scanner at a new target, keeping its other settings.
+// It must not be called while a scan is running.
+func (ps *PortScanner) SetHost(host string) {
+	if ps.connPool != nil {
+		ps.connPool.Close()
+	}
+	ps.retarget(host)
+}
+
+// retarget points ps at host with fresh state for it. The connection pool
+// is replaced without being closed, as ps may be a copy of the scanner
+// that owns it.
+func (ps *PortScanner) retarget(host string) {
+	ps.host = host
+	ps.ip = nil
+	if ps.describeCache != nil {
+		ps.describeCache = newDescribeCache(ps.describeCache.ttl)
+	}
+	ps.resetOpenCache()
+	if ps.connPool != nil {
+		ps.connPool = predictors.NewConnPool(ps.connPoolSize)
+	}
+	ps.knownFiltered = nil
+}
+
 func (ps *PortScanner) SetThreads(threads int) {
 	ps.threads = threads
 }
 
+// SetTimeout sets the connect timeout, see WithConnectTimeout.
 func (ps *PortScanner) SetTimeout(timeout time.Duration) {
 	ps.timeout = timeout
+	ps.resetOpenCache()
+}
+
+// resetOpenCache drops what WithOpenCache remembers, once it no longer
+// holds for the scanner's settings.
+func (ps *PortScanner) resetOpenCache() {
+	if ps.openCache != nil {
+		ps.openCache = newTTLCache[openResult](ps.openCache.ttl)
+	}
+}
+
+// ioTimeout is how long the predictors wait on each exchange with a
+// service: the read timeout, or the connect timeout when none is set.
+func (ps PortScanner) ioTimeout() time.Duration {
+	if ps.readTimeout > 0 {
+		return ps.readTimeout
+	}
+	return ps.timeout
 }
 
 func (ps *PortScanner) RegisterPredictor(predictor predictors.Predictor) {
@@ -52,103 +215,398 @@ func (ps *PortScanner) RegisterPredictor(predictor predictors.Predictor) {
 }
 
 func (ps PortScanner) IsOpen(port int) bool {
-	address := ps.hostPort(port)
-	conn, err := net.DialTimeout("tcp", address, ps.timeout)
-	if err != nil {
-		return false
-	}
-	conn.Close()
-	return true
+	open, _ := ps.IsOpenE(port)
+	return open
 }
 
+// GetOpenedPorts returns the open ports of [start, end], in order.
 func (ps PortScanner) GetOpenedPorts(start, end int) []int {
-	var openPorts []int
-	var mu sync.Mutex
+	open := newCollector[int](ps.threads)
+	ps.probePorts(context.Background(), ps.scanOrder(start, end), func(port int, _ net.Addr, _ time.Duration, err error) {
+		if err == nil {
+			open.add(port, port)
+		}
+	})
+
+	openPorts := open.items()
+	slices.Sort(openPorts)
+	return openPorts
+}
+
+// FirstOpenPort probes ports concurrently and returns the first one found
+// open, cancelling the probes still in flight.
+func (ps PortScanner) FirstOpenPort(ports []int) (int, bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var once sync.Once
+	first, found := 0, false
+	ps.probePorts(ctx, ports, func(port int, _ net.Addr, _ time.Duration, err error) {
+		if err == nil {
+			once.Do(func() {
+				first, found = port, true
+				cancel()
+			})
+		}
+	})
+	return first, found
+}
+
+// probePorts dials ports on ps.threads workers and hands the outcome of
+// each dial to visit, along with the time it took, which may be called
+// concurrently. Dispatching stops
+// as soon as ctx is cancelled; probes in flight are aborted.
+//
+// When the host turns out to be unreachable as a whole, the remaining
+// probes are abandoned and an error wrapping ErrHostUnreachable is
+// returned. The probes abandoned that way are not visited, and neither
+// are the ports WithPortFilter rejects.
+func (ps PortScanner) probePorts(ctx context.Context, ports []int, visit func(port int, local net.Addr, took time.Duration, err error)) error {
+	ports = ps.filterPorts(ports)
+	ctx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+
 	wg := sync.WaitGroup{}
 	sem := make(chan struct{}, ps.threads)
+	var throttle *resetThrottle
+	if ps.resetSensitivity > 0 {
+		throttle = newResetThrottle(ps.threads, ps.resetSensitivity, ps.logger, ps.host)
+	}
+	var reached atomic.Bool
+	breaker := ps.breaker()
+	var auto *aimdLimiter
+	if ps.autoMin > 0 {
+		auto = newAIMDLimiter(ps.autoMin, ps.threads)
+	}
 
-	for port := start; port <= end; port++ {
-		sem <- struct{}{}
+dispatch:
+	for i, port := range ports {
+		if throttle != nil {
+			throttle.acquire()
+		}
+		if auto != nil {
+			auto.acquire()
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
+		}
 		wg.Add(1)
-		go func(port int) {
+		go func(port int, first bool) {
 			defer wg.Done()
-			if ps.IsOpen(port) {
-				mu.Lock()
-				openPorts = append(openPorts, port)
-				mu.Unlock()
+			defer func() { <-sem }()
+			if first && ps.startupJitter > 0 {
+				// Spread the first wave so the dial rate ramps up.
+				select {
+				case <-time.After(rand.N(ps.startupJitter)):
+				case <-ctx.Done():
+					if throttle != nil {
+						throttle.release(ctx.Err())
+					}
+					if auto != nil {
+						auto.release(0, ctx.Err())
+					}
+					return
+				}
+			}
+			began := time.Now()
+			local, err := ps.safeProbe(ctx, port, breaker)
+			took := time.Since(began)
+			if throttle != nil {
+				throttle.release(err)
+			}
+			if auto != nil {
+				auto.release(took, err)
 			}
-			<-sem
-		}(port)
+			switch {
+			case err == nil || classifyDialError(err) == PortClosed:
+				reached.Store(true)
+			case isUnreachable(err) && !reached.Load():
+				abort(fmt.Errorf("%w: %s: %w", ErrHostUnreachable, ps.host, err))
+			}
+			if errors.Is(err, context.Canceled) && errors.Is(context.Cause(ctx), ErrHostUnreachable) {
+				return
+			}
+			visit(port, local, took, err)
+		}(port, i < ps.threads)
 	}
 
 	wg.Wait()
-	return openPorts
+	if cause := context.Cause(ctx); errors.Is(cause, ErrHostUnreachable) {
+		return cause
+	}
+	return nil
+}
+
+// scanOrder lists the ports of [start, end] in the order they should be
+// dispatched.
+func (ps PortScanner) scanOrder(start, end int) []int {
+	ports := portRange(start, end)
+	if ps.descending {
+		slices.Reverse(ports)
+	}
+	if ps.knownPortsFirst {
+		// A stable partition keeps the chosen direction within each group.
+		known := make([]int, 0, len(KNOWN_PORTS))
+		rest := make([]int, 0, len(ports))
+		for _, port := range ports {
+			if _, ok := KNOWN_PORTS[port]; ok {
+				known = append(known, port)
+			} else {
+				rest = append(rest, port)
+			}
+		}
+		ports = append(known, rest...)
+	}
+	return ports
+}
+
+func portRange(start, end int) []int {
+	if end < start {
+		return nil
+	}
+	ports := make([]int, 0, end-start+1)
+	for port := start; port <= end; port++ {
+		ports = append(ports, port)
+	}
+	return ports
 }
 
 func (ps PortScanner) hostPort(port int) string {
-	return fmt.Sprintf("%s:%d", ps.host, port)
+	if ps.ip != nil {
+		return ps.tcpAddr(port).String()
+	}
+	return net.JoinHostPort(ps.host, strconv.Itoa(port))
 }
 
+// tcpAddr is the address of port for scanners built from an IP.
+func (ps PortScanner) tcpAddr(port int) *net.TCPAddr {
+	return &net.TCPAddr{IP: ps.ip, Port: port}
+}
+
+// DescribePort describes the service on port. Ports that are not open are
+// reported as CLOSED or FILTERED without probing them further.
 func (ps PortScanner) DescribePort(port int) string {
+	return ps.DescribePortInfo(port).String()
+}
+
+// describeOpenMatch runs the predictors against a port already known to be
+// open and returns the details they reported along with the description.
+// Cancelling ctx cuts the predictors short; what they found by then is
+// returned but not cached.
+func (ps PortScanner) describeOpenMatch(ctx context.Context, port int) predictors.Match {
 	if !ps.usePredictor {
-		return ps.predictPort(port)
+		return predictors.Certain(ps.predictPort(port))
+	}
+	if ps.describeCache == nil {
+		return ps.runDescribe(ctx, port)
 	}
 
-	description := UNKNOWN
-	if ps.IsHttp(port) {
-		description = ps.PredictUsingPredictor(ps.hostPort(port))
-	} else {
-		assumed := ps.predictPort(port)
-		description = assumed
-		if assumed == UNKNOWN {
-			description = ps.PredictUsingPredictor(ps.hostPort(port))
-		}
-		if assumed == "MySQL" {
-			description = ps.getMySQLVersion(port, assumed)
+	key := ps.hostPort(port)
+	if match, ok := ps.describeCache.get(key); ok {
+		return match
+	}
+	match := ps.runDescribe(ctx, port)
+	if ctx.Err() == nil {
+		ps.describeCache.put(key, match)
+	}
+	return match
+}
+
+func (ps PortScanner) runDescribe(ctx context.Context, port int) predictors.Match {
+	// Every probe of this port shares one session, so HTTP predictors can
+	// reuse a single keep-alive connection. It is closed before moving on.
+	if ps.describeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ps.describeTimeout)
+		defer cancel()
+	}
+	session := ps.newSession(ps.hostPort(port))
+	session.Context = ctx
+	defer session.Close()
+
+	ps.applyProtocolTimeouts(session, port)
+	session.TLS = ps.IsHttps(port)
+	dc := &DescribeContext{Port: port, Session: session, Assumed: ps.predictPort(port), scanner: ps}
+	match := ps.describeChain(dc)
+	detected, assumed := dc.Detected, dc.Assumed
+	switch {
+	case !match.Found():
+		match.Description = UNKNOWN
+	case dc.mismatch != "":
+		match.Description += " [ " + dc.mismatch + " ]"
+	case detected != "" && match.Confidence < 1 && assumed != UNKNOWN && !conflicts(detected, assumed):
+		// Only the protocol was found: the label says more.
+		match.Description = assumed
+		match.Source = SourceKnownPorts
+	case conflicts(detected, assumed):
+		match.Description += " [ port " + strconv.Itoa(port) + " usually serves " + assumed + " ]"
+	}
+	if match.Description == UNKNOWN {
+		match.Source = ""
+		// The greeting, if any, is already cached from detection.
+		if banner, err := session.Banner(); err == nil && banner != "" {
+			match.Description += " [ " + payloadKind(banner) + " protocol ]"
 		}
 	}
 
-	return description
+	if ps.loadBalancerProbes > 1 {
+		web := strings.HasPrefix(detected, "HTTP") || strings.HasPrefix(match.Description, "web server")
+		if backends := ps.countBackends(ctx, port, web); backends > 1 {
+			match.Description += " [ " + loadBalancerNote(backends) + " ]"
+		}
+	}
+	ps.auditDescribe(dc, match)
+	return match
 }
 
 func (ps PortScanner) IsHttp(port int) bool {
 	return port == 80 || port == 8080
 }
 
+func (ps PortScanner) IsHttps(port int) bool {
+	return port == 443 || port == 8443
+}
+
+func (ps PortScanner) newSession(host string) *predictors.Session {
+	session := predictors.NewSession(host, ps.ioTimeout())
+	session.ConnectTimeout = ps.timeout
+	session.Network = ps.network
+	session.TLSConfig = ps.tlsConfig
+	if ps.maxResponseBytes > 0 {
+		session.MaxResponseBytes = ps.maxResponseBytes
+	}
+	if ps.userAgent != "" {
+		session.UserAgent = ps.userAgent
+	}
+	session.Authorization = ps.httpAuth
+	session.Resolver = ps.dnsResolver
+	session.SourceIP = ps.sourceIP
+	session.Control = ps.dialControl()
+	session.SourcePort = ps.sourcePort
+	session.GreetingBase = ps.greetingBase
+	session.FallbackDelay = ps.fallbackDelay()
+	session.FastOpen = ps.tcpFastOpen
+	session.Pool = ps.connPool
+	return session
+}
+
 func (ps PortScanner) PredictUsingPredictor(host string) string {
+	session := ps.newSession(host)
+	defer session.Close()
+	return ps.predictWithSession(session, ps.predictors)
+}
+
+// portPredictors returns the registered predictors dedicated to port.
+func (ps PortScanner) portPredictors(port int) []predictors.Predictor {
+	var matched []predictors.Predictor
 	for _, predictor := range ps.predictors {
-		conn, err := ps.openConn(host)
-		if err != nil {
+		pp, ok := predictor.(predictors.PortPredictor)
+		if !ok || isUDPPredictor(predictor) {
 			continue
 		}
-		defer conn.Close()
-		if result := predictor.Predict(host); len(result) > 0 {
-			return result
+		for _, p := range pp.Ports() {
+			if p == port {
+				matched = append(matched, predictor)
+				break
+			}
 		}
 	}
+	return matched
+}
+
+// filterPorts returns the ports WithPortFilter accepts, all of them
+// without a filter.
+func (ps PortScanner) filterPorts(ports []int) []int {
+	if ps.portFilter == nil {
+		return ports
+	}
+	var kept []int
+	for _, port := range ports {
+		if ps.portFilter(port) {
+			kept = append(kept, port)
+		}
+	}
+	return kept
+}
+
+// genericPredictors returns the registered predictors that are not tied to
+// specific ports.
+func (ps PortScanner) genericPredictors() []predictors.Predictor {
+	var generic []predictors.Predictor
+	for _, predictor := range ps.predictors {
+		if _, ok := predictor.(predictors.PortPredictor); !ok && !isUDPPredictor(predictor) {
+			generic = append(generic, predictor)
+		}
+	}
+	return generic
+}
+
+func (ps PortScanner) predictWithSession(session *predictors.Session, candidates []predictors.Predictor) string {
+	if match := ps.bestMatch(session, candidates); match.Found() {
+		return match.Description
+	}
 	return UNKNOWN
 }
 
-func (ps PortScanner) openConn(host string) (net.Conn, error) {
-	tcpAddr, err := net.ResolveTCPAddr("tcp4", host)
-	if err != nil {
-		return nil, err
+// bestMatch returns the match with the highest confidence among candidates.
+// Ties go to the predictor registered first, and a fully confident match
+// ends the search early.
+func (ps PortScanner) bestMatch(session *predictors.Session, candidates []predictors.Predictor) predictors.Match {
+	var best predictors.Match
+	for _, predictor := range candidates {
+		if session.Context != nil && session.Context.Err() != nil {
+			break
+		}
+		match := ps.runPredictor(predictor, session)
+		if match.Found() && match.Confidence > best.Confidence {
+			best = match
+			if best.Confidence >= 1 {
+				break
+			}
+		}
 	}
-	return net.DialTimeout("tcp", tcpAddr.String(), ps.timeout)
+	return best
 }
 
-func (ps PortScanner) getMySQLVersion(port int, assumed string) string {
-	conn, err := ps.openConn(ps.hostPort(port))
+// runPredictor invokes a single predictor, turning a panic into an empty
+// match so a faulty predictor cannot abort the scan.
+func (ps PortScanner) runPredictor(predictor predictors.Predictor, session *predictors.Session) (match predictors.Match) {
+	defer func() {
+		if r := recover(); r != nil {
+			if ps.logger != nil {
+				ps.logger.Error("predictor panicked",
+					"predictor", fmt.Sprintf("%T", predictor),
+					"host", session.Host,
+					"panic", r)
+			}
+			match = predictors.Match{}
+		}
+	}()
+
+	switch p := predictor.(type) {
+	case predictors.MatchPredictor:
+		return p.PredictMatch(session)
+	case predictors.SessionPredictor:
+		return predictors.Certain(p.PredictSession(session))
+	}
+	return predictors.Certain(predictor.Predict(session.Host))
+}
+
+func (ps PortScanner) getMySQLVersion(session *predictors.Session, assumed string) string {
+	conn, err := session.Dial()
 	if err != nil {
 		return assumed
 	}
 	defer conn.Close()
 
-	conn.SetDeadline(time.Now().Add(3 * time.Second))
-
+	// The dial set the read timeout as deadline, or an earlier one if
+	// WithScanTimeout leaves less; WithAdaptiveGreeting shortens it.
+	session.AwaitGreeting(conn)
 	result := make([]byte, 20)
-	if _, err := conn.Read(result); err == nil {
-		return assumed + " version: " + string(result)
+	if n, err := session.Limit(conn).Read(result); err == nil {
+		return assumed + " version: " + string(result[:n])
 	}
 	return assumed
 }
@@ -172,33 +630,57 @@ var KNOWN_PORTS = map[int]string{
 	143:   "IMAP",
 	150:   "SQL-Net?",
 	194:   "IRC",
+	389:   "LDAP",
 	443:   "HTTPS",
 	445:   "Samba",
 	465:   "SMTP over SSL",
+	587:   "SMTP Submission",
 	554:   "RTSP",
 	5800:  "VNC Remote Desktop",
+	5900:  "VNC",
 	631:   "CUPS",
+	636:   "LDAP over SSL",
 	993:   "IMAP over SSL",
 	995:   "POP3 over SSL",
+	1080:  "SOCKS Proxy",
 	1433:  "Microsoft SQL Server",
 	1434:  "Microsoft SQL Monitor",
+	1883:  "MQTT",
+	2181:  "Zookeeper",
+	2375:  "Docker API",
+	2376:  "Docker API over TLS",
 	3306:  "MySQL",
 	3389:  "Remote Desktop Protocol (RDP)",
 	3396:  "Novell NDPS Printer Agent",
 	3535:  "SMTP (Alternate)",
 	5432:  "PostgreSQL",
 	6379:  "Redis",
+	6667:  "IRC",
+	6697:  "IRC over SSL",
 	8080:  "HTTP Alternate",
+	8443:  "HTTPS Alternate",
+	8086:  "InfluxDB",
+	8883:  "MQTT over TLS",
+	9090:  "Prometheus",
+	9092:  "Kafka",
+	9100:  "node_exporter",
 	9160:  "Cassandra",
+	9418:  "Git",
 	9200:  "Elasticsearch",
 	11211: "Memcached",
 	27017: "MongoDB",
 	28017: "MongoDB Web Admin",
+	50051: "gRPC",
 }
 
 func (ps PortScanner) predictPort(port int) string {
 	if desc, exists := KNOWN_PORTS[port]; exists {
 		return desc
 	}
+	if ps.useSystemServices {
+		if name, exists := systemService(port); exists {
+			return name
+		}
+	}
 	return UNKNOWN
 }
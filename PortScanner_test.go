@@ -0,0 +1,100 @@
+package portscanner
+
+import (
+	"bytes"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// listen serves every connection to a local listener with serve, and
+// returns the port it listens on. The listener is closed with the test.
+func listen(t testing.TB, serve func(conn net.Conn)) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// drip writes an HTTP response whose headers never end, one byte at a
+// time, until the client goes away.
+func drip(conn net.Conn) {
+	response := []byte("HTTP/1.1 200 OK\r\n")
+	for i := 0; ; i++ {
+		if i == len(response) {
+			response, i = []byte("X-Drip: 1\r\n"), 0
+		}
+		if _, err := conn.Write(response[i : i+1]); err != nil {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestScanTimeoutCutsSlowDrip(t *testing.T) {
+	port := listen(t, drip)
+
+	const budget = 500 * time.Millisecond
+	ps := NewPortScanner("127.0.0.1", time.Second, 1)
+	if err := ps.Apply(WithReadTimeout(time.Minute), WithScanTimeout(budget)); err != nil {
+		t.Fatal(err)
+	}
+
+	began := time.Now()
+	ps.DescribePort(port)
+	// The dial confirming the port is open comes on top of the budget.
+	if took := time.Since(began); took > budget+time.Second {
+		t.Fatalf("DescribePort took %s with a %s scan timeout", took, budget)
+	}
+}
+
+// panickingPredictor is a faulty predictor dedicated to port.
+type panickingPredictor struct {
+	predictors.BasePredictor
+	port int
+}
+
+func (p *panickingPredictor) Ports() []int {
+	return []int{p.port}
+}
+
+func (p *panickingPredictor) Predict(host string) string {
+	panic("predictor bug")
+}
+
+func TestPanickingPredictorIsSkipped(t *testing.T) {
+	port := listen(t, func(conn net.Conn) { conn.Read(make([]byte, 1)) })
+
+	var logs bytes.Buffer
+	ps := NewPortScanner("127.0.0.1", time.Second, 1)
+	if err := ps.Apply(WithLogger(slog.New(slog.NewTextHandler(&logs, nil))), WithReadTimeout(100*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	ps.RegisterPredictor(&panickingPredictor{port: port})
+
+	if desc := ps.DescribePort(port); !strings.HasPrefix(desc, UNKNOWN) {
+		t.Fatalf("DescribePort = %q, want %s", desc, UNKNOWN)
+	}
+	if !strings.Contains(logs.String(), "predictor panicked") {
+		t.Fatalf("panic not logged: %s", logs.String())
+	}
+}
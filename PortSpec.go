@@ -0,0 +1,91 @@
+package portscanner
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MinPort = 1
+	MaxPort = 65535
+)
+
+// ParsePortSpec parses a comma separated list of ports and inclusive
+// ranges, such as "22,80,8000-8100", into a sorted list of distinct ports.
+// Ports outside 1-65535, reversed ranges and malformed entries are
+// rejected.
+func ParsePortSpec(spec string) ([]int, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, fmt.Errorf("portscanner: empty port spec")
+	}
+
+	seen := map[int]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("portscanner: empty entry in port spec %q", spec)
+		}
+
+		start, end, err := parsePortRange(part)
+		if err != nil {
+			return nil, err
+		}
+		for port := start; port <= end; port++ {
+			seen[port] = true
+		}
+	}
+
+	ports := make([]int, 0, len(seen))
+	for port := range seen {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	return ports, nil
+}
+
+func parsePortRange(part string) (int, int, error) {
+	lo, hi, isRange := strings.Cut(part, "-")
+	start, err := parsePort(lo)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		return start, start, nil
+	}
+
+	end, err := parsePort(hi)
+	if err != nil {
+		return 0, 0, err
+	}
+	if end < start {
+		return 0, 0, fmt.Errorf("portscanner: reversed port range %q", part)
+	}
+	return start, end, nil
+}
+
+func parsePort(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("portscanner: missing port number")
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("portscanner: invalid port %q", s)
+		}
+	}
+	// Anything longer than five digits is out of range; checking first
+	// keeps huge inputs away from strconv.
+	if len(strings.TrimLeft(s, "0")) > 5 {
+		return 0, fmt.Errorf("portscanner: port %s out of range %d-%d", s, MinPort, MaxPort)
+	}
+	port, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("portscanner: invalid port %q", s)
+	}
+	if port < MinPort || port > MaxPort {
+		return 0, fmt.Errorf("portscanner: port %d out of range %d-%d", port, MinPort, MaxPort)
+	}
+	return port, nil
+}
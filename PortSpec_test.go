@@ -0,0 +1,29 @@
+package portscanner
+
+import "testing"
+
+func FuzzParsePortSpec(f *testing.F) {
+	for _, seed := range []string{"22", "22,80,443", "8000-8100", " 1 - 65535 ", "0", "65536", "10-1", "1,,2", "-", "99999999999999999999", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, spec string) {
+		ports, err := ParsePortSpec(spec)
+		if err != nil {
+			if ports != nil {
+				t.Fatalf("ParsePortSpec(%q) returned ports along with error %v", spec, err)
+			}
+			return
+		}
+		if len(ports) == 0 {
+			t.Fatalf("ParsePortSpec(%q) accepted a spec without ports", spec)
+		}
+		for i, port := range ports {
+			if port < MinPort || port > MaxPort {
+				t.Fatalf("ParsePortSpec(%q) accepted port %d", spec, port)
+			}
+			if i > 0 && port <= ports[i-1] {
+				t.Fatalf("ParsePortSpec(%q) = %v, not sorted and distinct", spec, ports)
+			}
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package portscanner
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// PRESETS maps application names to the ports they use, for ScanPreset.
+// Add entries to teach the scanner about other applications.
+var PRESETS = map[string][]int{
+	"web":           {80, 443, 8000, 8008, 8080, 8443, 8888},
+	"databases":     {1433, 1521, 3306, 5432, 6379, 9042, 9200, 11211, 27017},
+	"mail":          {25, 110, 143, 465, 587, 993, 995},
+	"k8s":           {443, 2379, 2380, 6443, 10250, 10256, 10257, 10259, 30000},
+	"mysql":         {3306, 33060},
+	"mysql-cluster": {1186, 3306, 33060, 33061},
+	"postgres":      {5432, 6432},
+	"remote-access": {22, 23, 3389, 5900},
+	"windows":       {88, 135, 139, 389, 445, 636, 3268, 3389, 5985, 5986},
+	"docker":        {2375, 2376, 2377, 7946},
+	"monitoring":    {3000, 9090, 9093, 9100},
+	"messaging":     {1883, 4369, 5672, 8883, 9092, 15672},
+}
+
+// PresetNames lists the names of PRESETS, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(PRESETS))
+	for name := range PRESETS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ScanPreset scans the ports PRESETS lists for name, as Scan does for a
+// range.
+func (ps PortScanner) ScanPreset(name string) (Report, error) {
+	ports, ok := PRESETS[name]
+	if !ok {
+		return Report{}, fmt.Errorf("portscanner: unknown preset %q", name)
+	}
+	ports = slices.Clone(ports)
+	slices.Sort(ports)
+	return ps.scanPorts(slices.Compact(ports)), nil
+}
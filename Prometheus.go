@@ -0,0 +1,60 @@
+package portscanner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePrometheus renders the report in the Prometheus text exposition
+// format, suitable for the node_exporter textfile collector.
+func (r Report) WritePrometheus(w io.Writer) error {
+	return WritePrometheus(w, r)
+}
+
+// WritePrometheus renders several reports as a single exposition, so each
+// metric family is declared once.
+func WritePrometheus(w io.Writer, reports ...Report) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "# HELP port_open Whether the port was found open by the last scan.")
+	fmt.Fprintln(bw, "# TYPE port_open gauge")
+	for _, r := range reports {
+		for _, res := range r.Results {
+			if res.State != PortOpen {
+				continue
+			}
+			// Every series names its protocol, the same port may be open
+			// over TCP and UDP.
+			protocol := res.Protocol
+			if protocol == "" {
+				protocol = ProtocolTCP
+			}
+			fmt.Fprintf(bw, "port_open{host=\"%s\",port=\"%d\",protocol=\"%s\",service=\"%s\"} 1\n",
+				escapeLabel(r.Host), res.Port, protocol, escapeLabel(res.Service))
+		}
+	}
+
+	fmt.Fprintln(bw, "# HELP port_scan_duration_seconds How long the last scan took.")
+	fmt.Fprintln(bw, "# TYPE port_scan_duration_seconds gauge")
+	for _, r := range reports {
+		fmt.Fprintf(bw, "port_scan_duration_seconds{host=\"%s\"} %s\n",
+			escapeLabel(r.Host), strconv.FormatFloat(r.Duration.Seconds(), 'f', -1, 64))
+	}
+
+	fmt.Fprintln(bw, "# HELP port_scan_errors Ports that could not be probed during the last scan.")
+	fmt.Fprintln(bw, "# TYPE port_scan_errors gauge")
+	for _, r := range reports {
+		fmt.Fprintf(bw, "port_scan_errors{host=\"%s\"} %d\n", escapeLabel(r.Host), r.Errors)
+	}
+
+	return bw.Flush()
+}
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
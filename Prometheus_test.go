@@ -0,0 +1,25 @@
+package portscanner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWritePrometheusLabelsProtocol(t *testing.T) {
+	report := Report{Host: "db", Results: []ScanResult{
+		{Port: 53, State: PortOpen, Service: "DNS"},
+		{Port: 53, State: PortOpen, Service: "DNS", Protocol: ProtocolUDP},
+	}}
+	var out strings.Builder
+	if err := report.WritePrometheus(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`port_open{host="db",port="53",protocol="tcp",service="DNS"} 1`,
+		`port_open{host="db",port="53",protocol="udp",service="DNS"} 1`,
+	} {
+		if !strings.Contains(out.String(), want+"\n") {
+			t.Errorf("missing %s in:\n%s", want, out.String())
+		}
+	}
+}
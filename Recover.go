@@ -0,0 +1,64 @@
+package portscanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"runtime/debug"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// PortPanic is a panic recovered from the worker probing or describing a
+// port, e.g. in a connect callback or a custom predictor. The scan goes
+// on without that port.
+type PortPanic struct {
+	Port  int
+	Value any
+	Stack []byte
+}
+
+func (p *PortPanic) Error() string {
+	return fmt.Sprintf("portscanner: panic on port %d: %v", p.Port, p.Value)
+}
+
+// Err sums up what went wrong with the scan: Aborted, SinkErr, then
+// every recovered panic. It is nil for a scan that ran to completion.
+func (r Report) Err() error {
+	errs := []error{r.Aborted, r.SinkErr}
+	for _, p := range r.Panics {
+		errs = append(errs, p)
+	}
+	return errors.Join(errs...)
+}
+
+// recovered turns a recovered panic value into a PortPanic, and logs it.
+func (ps PortScanner) recovered(port int, value any, during string) *PortPanic {
+	p := &PortPanic{Port: port, Value: value, Stack: debug.Stack()}
+	if ps.logger != nil {
+		ps.logger.Error(during+" panicked", "host", ps.host, "port", port, "panic", value)
+	}
+	return p
+}
+
+// safeProbe is probeGuarded reporting a panic as the error of the probe.
+func (ps PortScanner) safeProbe(ctx context.Context, port int, breaker *localBreaker) (local net.Addr, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			local, err = nil, ps.recovered(port, r, "probe")
+		}
+	}()
+	return ps.probeGuarded(ctx, port, breaker)
+}
+
+// safeDescribe is describeOpenMatch returning a panic instead of raising
+// it, along with an empty match.
+func (ps PortScanner) safeDescribe(ctx context.Context, port int) (match predictors.Match, panicked *PortPanic) {
+	defer func() {
+		if r := recover(); r != nil {
+			match, panicked = predictors.Match{}, ps.recovered(port, r, "describe")
+		}
+	}()
+	return ps.describeOpenMatch(ctx, port), nil
+}
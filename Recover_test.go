@@ -0,0 +1,37 @@
+package portscanner
+
+import (
+	"net"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestScanRecoversDialerPanic(t *testing.T) {
+	silent := func(conn net.Conn) { conn.Read(make([]byte, 1)) }
+	healthy, faulty := listen(t, silent), listen(t, silent)
+
+	ps := NewPortScanner("127.0.0.1", time.Second, 2)
+	// A dialer that fails in the worst way on one port.
+	control := func(network, address string, c syscall.RawConn) error {
+		if address == net.JoinHostPort("127.0.0.1", strconv.Itoa(faulty)) {
+			panic("dialer bug")
+		}
+		return nil
+	}
+	if err := ps.Apply(WithSocketOptions(control), WithReadTimeout(100*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	report := ps.scanPorts([]int{healthy, faulty})
+	if len(report.Panics) != 1 || report.Panics[0].Port != faulty {
+		t.Fatalf("Panics = %v, want one on port %d", report.Panics, faulty)
+	}
+	if report.Err() == nil {
+		t.Fatal("Err() = nil despite the panic")
+	}
+	if len(report.Results) != 1 || report.Results[0].Port != healthy || report.Results[0].State != PortOpen {
+		t.Fatalf("Results = %v, want port %d open", report.Results, healthy)
+	}
+}
@@ -0,0 +1,61 @@
+package portscanner
+
+import (
+	"fmt"
+	"time"
+)
+
+// RefusalSummary tells how the ports that are not open answered, which
+// shows how the host is firewalled: a closed port refuses a connection
+// with a reset at once, while a firewall dropping packets leaves the
+// probe to time out.
+type RefusalSummary struct {
+	// Refused counts the probes refused with a reset.
+	Refused int
+	// RefusedWithin is the LatencyBuckets bound within which half of the
+	// refusals came back, or zero without refusals.
+	RefusedWithin time.Duration
+	// TimedOut counts the ports whose probe got no answer at all, those
+	// of Report.Filtered.
+	TimedOut int
+}
+
+// ClosedPort is how the probe of a port that is not open ended.
+type ClosedPort struct {
+	Port int
+	// Refused is set when the connection was refused with a reset; the
+	// probe timed out otherwise.
+	Refused bool
+	// Latency is how long the probe took to be refused or to time out.
+	Latency time.Duration
+}
+
+// summarizeRefusals builds the RefusalSummary of a scan from its metrics
+// and the ports that timed out.
+func summarizeRefusals(metrics ScanMetrics, timedOut int) RefusalSummary {
+	summary := RefusalSummary{Refused: int(metrics.Closed), TimedOut: timedOut}
+	var seen int64
+	for i, n := range metrics.RefusedLatency {
+		if seen += n; n > 0 && seen*2 >= metrics.Closed {
+			summary.RefusedWithin = LatencyBuckets[min(i, len(LatencyBuckets)-1)]
+			break
+		}
+	}
+	return summary
+}
+
+// String reads the summary, e.g. "450 ports refused within 1ms, 200 timed
+// out: likely a stateful firewall dropping the timed out ports". It is
+// empty when every port probed was open.
+func (s RefusalSummary) String() string {
+	refused := fmt.Sprintf("%d ports refused within %s", s.Refused, s.RefusedWithin)
+	switch {
+	case s.Refused > 0 && s.TimedOut > 0:
+		return fmt.Sprintf("%s, %d timed out: likely a stateful firewall dropping the timed out ports", refused, s.TimedOut)
+	case s.Refused > 0:
+		return refused + ": no filtering seen"
+	case s.TimedOut > 0:
+		return fmt.Sprintf("%d ports timed out: the host, or the path to it, drops every probe not reaching an open port", s.TimedOut)
+	}
+	return ""
+}
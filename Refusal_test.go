@@ -0,0 +1,37 @@
+package portscanner
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// freePort returns a local port nothing listens on.
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestScanRecordsRefusedPorts(t *testing.T) {
+	open := listen(t, func(conn net.Conn) {})
+	refused := freePort(t)
+
+	ps := NewPortScanner("127.0.0.1", time.Second, 2)
+	ps.TogglePredictor(false)
+	report := ps.ScanPorts([]int{open, refused})
+
+	if len(report.ClosedPorts) != 1 {
+		t.Fatalf("ClosedPorts = %v, want port %d only", report.ClosedPorts, refused)
+	}
+	if c := report.ClosedPorts[0]; c.Port != refused || !c.Refused || c.Latency <= 0 {
+		t.Fatalf("ClosedPorts[0] = %+v, want port %d refused with a latency", c, refused)
+	}
+	if report.Refusals.Refused != 1 {
+		t.Fatalf("Refusals = %+v, want one refusal", report.Refusals)
+	}
+}
@@ -0,0 +1,273 @@
+package portscanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+type ScanResult struct {
+	Port     int
+	State    PortState
+	Service  string
+	Category string
+	// Protocol is ProtocolTCP or ProtocolUDP.
+	Protocol string
+	// Software is the product and version the predictors identified, if
+	// any.
+	Software predictors.Software
+	// CPE is the CPE 2.3 name of Software, for matching against
+	// vulnerability databases, when its product and version are known.
+	CPE string
+	// Unauthenticated is set when the service answered a command without
+	// requiring credentials, e.g. a Redis PING without AUTH.
+	Unauthenticated bool
+	// LocalAddr is the address the probe of an open port was made from,
+	// which shows the interface the scan went out of. It is nil for ports
+	// that are not open.
+	LocalAddr net.Addr
+	// Capabilities lists what the service advertises, e.g. STARTTLS and
+	// the AUTH mechanisms of a mail server. It is nil for services whose
+	// predictors do not enumerate them.
+	Capabilities []string
+	// Host is the host the result is about, set by ScanTargets, whose
+	// results span several hosts.
+	Host string
+}
+
+// String renders the result as "port/protocol state service", e.g.
+// "22/tcp open SSH (SSH-2.0-OpenSSH_9.6)", the protocol defaulting to
+// tcp and the service left out when there is none. The format is stable:
+// fields are separated by single spaces and only the service may contain
+// spaces.
+func (r ScanResult) String() string {
+	protocol := r.Protocol
+	if protocol == "" {
+		protocol = ProtocolTCP
+	}
+	s := strconv.Itoa(r.Port) + "/" + protocol + " " + r.State.String()
+	if r.Service != "" {
+		s += " " + r.Service
+	}
+	return s
+}
+
+// MarshalText returns String, for templates, logs and other text
+// contexts. It also makes encoding/json render a ScanResult as that
+// string; JSONFormatter writes the full record.
+func (r ScanResult) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+type Report struct {
+	Host    string
+	Results []ScanResult
+	// Hostnames are the PTR names of Host, when it is an address and
+	// WithReverseDNS is set.
+	Hostnames []string
+
+	StartedAt time.Time
+	Duration  time.Duration
+	// Errors counts the ports whose dial failed for a reason other than a
+	// refusal or a timeout, e.g. an unreachable network.
+	Errors int
+	// HostDown is set when the host failed the liveness check and was not
+	// scanned.
+	HostDown bool
+	// Metrics covers the probes of this scan only.
+	Metrics ScanMetrics
+	// SecondPass lists the open ports that timed out at first and only
+	// answered when retried by WithSecondPass.
+	SecondPass []int
+	// Aborted is set, wrapping ErrHostUnreachable, when the scan was
+	// abandoned because the host could not be reached at all. Results
+	// then only cover the ports probed until then.
+	Aborted error
+	// Filtered lists the ports whose probe timed out, in order.
+	Filtered []int
+	// OS is the operating system the services' banners suggest.
+	OS OSGuess
+	// Panics lists the panics recovered while probing or describing
+	// ports, ordered by port. Results cover the other ports as usual; a
+	// port whose description panicked is reported open without a
+	// service.
+	Panics []*PortPanic
+	// Stacks are the applications the combination of open ports suggests,
+	// per STACK_RULES.
+	Stacks []Stack
+	// Refusals contrasts the ports refused at once with those that timed
+	// out, as a hint of the firewalling in front of the host.
+	Refusals RefusalSummary
+	// ClosedPorts records how the probe of each port that is not open
+	// ended, refused or timed out, and how long it took, ordered by port.
+	// Ports whose dial failed otherwise, counted in Errors, are left out.
+	ClosedPorts []ClosedPort
+	// SinkErr is the first error of the WithResultSink sink, after which
+	// the results of the scan were no longer written.
+	SinkErr error
+}
+
+type probeOutcome struct {
+	port  int
+	local net.Addr
+	took  time.Duration
+	err   error
+}
+
+// Scan finds the open ports in [start, end] and describes each of them.
+// Results are ordered by port.
+func (ps PortScanner) Scan(start, end int) Report {
+	return ps.scanPorts(ps.scanOrder(start, end))
+}
+
+// ScanPorts is Scan over a list of distinct ports rather than a range, such
+// as those of ParsePortSpec or a Config. Ports are probed in the order
+// given; results are ordered by port.
+func (ps PortScanner) ScanPorts(ports []int) Report {
+	return ps.scanPorts(ports)
+}
+
+func (ps PortScanner) scanPorts(ports []int) Report {
+	report := Report{Host: ps.host, StartedAt: time.Now()}
+	// The reverse lookup runs while the ports are probed.
+	var names chan []string
+	if ps.reverseLookup {
+		names = make(chan []string, 1)
+		go func() { names <- ps.reverseDNS() }()
+	}
+	// ps is a copy: scan probes count towards both totals.
+	ps.metrics = newMetricsRecorder(ps.metrics)
+
+	var openPorts, timedOut []int
+	locals := map[int]net.Addr{}
+	closed := map[int]ClosedPort{}
+	probe := func(scanner PortScanner, ports []int) {
+		outcomes := newCollector[probeOutcome](scanner.threads)
+		report.Aborted = scanner.probePorts(context.Background(), ports, func(port int, local net.Addr, took time.Duration, err error) {
+			outcomes.add(port, probeOutcome{port, local, took, err})
+		})
+		for _, o := range outcomes.items() {
+			var panicked *PortPanic
+			switch {
+			case errors.As(o.err, &panicked):
+				report.Panics = append(report.Panics, panicked)
+			case o.err == nil:
+				openPorts = append(openPorts, o.port)
+				locals[o.port] = o.local
+				// Found on the second pass.
+				delete(closed, o.port)
+			case classifyDialError(o.err) == PortClosed:
+				closed[o.port] = ClosedPort{Port: o.port, Refused: true, Latency: o.took}
+			case isTimeout(o.err):
+				timedOut = append(timedOut, o.port)
+				closed[o.port] = ClosedPort{Port: o.port, Latency: o.took}
+			default:
+				report.Errors++
+			}
+		}
+	}
+	probe(ps, ports)
+
+	if ps.secondPassTimeout > 0 && len(timedOut) > 0 && report.Aborted == nil {
+		retry := ps
+		retry.timeout = ps.secondPassTimeout
+		retry.knownFiltered = nil
+		retry.threads = max(1, ps.threads/4)
+		firstPass := len(openPorts)
+		ports := timedOut
+		timedOut = nil
+		probe(retry, ports)
+
+		report.SecondPass = append([]int(nil), openPorts[firstPass:]...)
+		sort.Ints(report.SecondPass)
+	}
+
+	sort.Ints(timedOut)
+	report.Filtered = timedOut
+	report.ClosedPorts = make([]ClosedPort, 0, len(closed))
+	for _, c := range closed {
+		report.ClosedPorts = append(report.ClosedPorts, c)
+	}
+	sort.Slice(report.ClosedPorts, func(i, j int) bool { return report.ClosedPorts[i].Port < report.ClosedPorts[j].Port })
+
+	sort.Ints(openPorts)
+	sink := ps.newResultWriter(ps.host)
+	var described func(port int, match predictors.Match)
+	if sink != nil {
+		described = func(port int, match predictors.Match) {
+			sink.write(openTCPResult(port, match, locals[port]))
+		}
+	}
+	matches, panics := ps.describePortMatches(openPorts, described)
+	report.Panics = append(report.Panics, panics...)
+	sort.Slice(report.Panics, func(i, j int) bool { return report.Panics[i].Port < report.Panics[j].Port })
+	for _, port := range openPorts {
+		report.Results = append(report.Results, openTCPResult(port, matches[port], locals[port]))
+	}
+	report.Stacks = InferStacks(openPorts)
+	report.OS = GuessOS(report.Results)
+	if sink != nil {
+		report.SinkErr = sink.failure()
+		report.Results = nil
+	}
+	report.Duration = time.Since(report.StartedAt)
+	report.Metrics = ps.metrics.snapshot()
+	report.Refusals = summarizeRefusals(report.Metrics, len(report.Filtered))
+	if names != nil {
+		report.Hostnames = <-names
+	}
+	return report
+}
+
+// openTCPResult is the result of an open TCP port described as match.
+func openTCPResult(port int, match predictors.Match, local net.Addr) ScanResult {
+	return ScanResult{
+		Port:     port,
+		State:    PortOpen,
+		Service:  match.Description,
+		Category: portCategory(port),
+		Protocol: ProtocolTCP,
+		Software: match.Software,
+		CPE:      match.Software.CPE(),
+
+		Unauthenticated: match.Unauthenticated,
+		LocalAddr:       local,
+		Capabilities:    match.Capabilities,
+	}
+}
+
+// ScanAddresses scans [start, end] and returns one report per target. With
+// WithScanAllAddresses enabled the host is resolved and every address is
+// scanned separately, keyed by IP; otherwise the only key is the host.
+func (ps PortScanner) ScanAddresses(start, end int) (map[string]Report, error) {
+	if !ps.scanAllAddresses {
+		return map[string]Report{ps.host: ps.Scan(start, end)}, nil
+	}
+
+	addrs, err := ps.resolver().LookupIP(context.Background(), ipNetwork(ps.network), ps.host)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		hosts = append(hosts, addr.String())
+	}
+	return ps.ScanHosts(hosts, start, end), nil
+}
+
+// ipNetwork maps a dial network to the matching address-lookup network.
+func ipNetwork(network string) string {
+	switch network {
+	case "tcp4":
+		return "ip4"
+	case "tcp6":
+		return "ip6"
+	}
+	return "ip"
+}
@@ -0,0 +1,44 @@
+package portscanner
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// maxPTRLookups bounds the reverse lookups in flight during multi-host
+	// scans, so a sweep does not flood the resolver.
+	maxPTRLookups = 16
+	// ptrLookupTimeout bounds each reverse lookup.
+	ptrLookupTimeout = 5 * time.Second
+)
+
+// reverseDNS returns the PTR names of the scanned address, without their
+// trailing dot, or nil when the target is a host name, has no PTR record
+// or the lookup fails.
+func (ps PortScanner) reverseDNS() []string {
+	ip := ps.ip
+	if ip == nil {
+		ip = net.ParseIP(ps.host)
+	}
+	if ip == nil {
+		return nil
+	}
+	if ps.ptrLimiter != nil {
+		ps.ptrLimiter <- struct{}{}
+		defer func() { <-ps.ptrLimiter }()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ptrLookupTimeout)
+	defer cancel()
+	names, err := ps.resolver().LookupAddr(ctx, ip.String())
+	if err != nil {
+		return nil
+	}
+	for i, name := range names {
+		names[i] = strings.TrimSuffix(name, ".")
+	}
+	return names
+}
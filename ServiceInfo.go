@@ -0,0 +1,126 @@
+package portscanner
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// Values of ServiceInfo.Source.
+const (
+	SourceProbe      = "probe"
+	SourcePredictor  = "predictor"
+	SourceBanner     = "banner"
+	SourceKnownPorts = "known-ports"
+)
+
+// ServiceInfo is what DescribePort finds, split into fields.
+type ServiceInfo struct {
+	// Name is the service, e.g. "SSH" or "Redis": the port label unless
+	// the service turned out to be another one, in which case it is the
+	// first word of the description. It is empty for ports that are not
+	// open and unknown services.
+	Name    string
+	Product string
+	Version string
+	// Extra holds the other details the predictors reported: "os",
+	// "cpe", "capabilities" (comma separated) and "unauthenticated".
+	Extra map[string]string
+	// Source tells how the service was identified: SourceProbe for a
+	// WithProbe match, SourcePredictor, SourceBanner for what the service
+	// announced on its own, or SourceKnownPorts when only the port number
+	// spoke. It is empty when nothing did.
+	Source string
+
+	description string
+}
+
+// String returns the description DescribePort returns.
+func (si ServiceInfo) String() string {
+	return si.description
+}
+
+// DescribePortInfo is DescribePort returning the details behind the
+// description.
+func (ps PortScanner) DescribePortInfo(port int) ServiceInfo {
+	if !ps.usePredictor {
+		return newServiceInfo(port, predictors.Match{Description: ps.predictPort(port), Source: SourceKnownPorts})
+	}
+
+	switch state, _ := ps.State(port); state {
+	case PortClosed:
+		return ServiceInfo{description: CLOSED}
+	case PortFiltered:
+		return ServiceInfo{description: FILTERED}
+	}
+	return newServiceInfo(port, ps.describeOpenMatch(context.Background(), port))
+}
+
+// DescribeRange probes [start, end] and describes each port found open
+// right away, on the same SetThreads workers, so an open port is dialed
+// once to find it rather than again to describe it. The result holds the
+// open ports only.
+func (ps PortScanner) DescribeRange(start, end int) map[int]ServiceInfo {
+	infos := map[int]ServiceInfo{}
+	var mu sync.Mutex
+	ctx := context.Background()
+	ps.probePorts(ctx, ps.scanOrder(start, end), func(port int, _ net.Addr, _ time.Duration, err error) {
+		if err != nil {
+			return
+		}
+		var info ServiceInfo
+		if !ps.usePredictor {
+			info = newServiceInfo(port, predictors.Match{Description: ps.predictPort(port), Source: SourceKnownPorts})
+		} else if match, panicked := ps.safeDescribe(ctx, port); panicked == nil {
+			info = newServiceInfo(port, match)
+		} else {
+			info = ServiceInfo{description: UNKNOWN}
+		}
+		mu.Lock()
+		infos[port] = info
+		mu.Unlock()
+	})
+	return infos
+}
+
+func newServiceInfo(port int, match predictors.Match) ServiceInfo {
+	si := ServiceInfo{
+		Product:     match.Software.Product,
+		Version:     match.Software.Version,
+		Extra:       map[string]string{},
+		Source:      match.Source,
+		description: match.Description,
+	}
+	if match.Description == UNKNOWN || strings.HasPrefix(match.Description, UNKNOWN+" ") {
+		si.Source = ""
+	}
+
+	switch {
+	case si.Source == "":
+	case KNOWN_PORTS[port] != "" && !strings.Contains(match.Description, " usually serves "):
+		si.Name = KNOWN_PORTS[port]
+	case si.Product != "":
+		si.Name = si.Product
+	default:
+		si.Name, _, _ = strings.Cut(match.Description, " ")
+	}
+
+	if match.Software.OS != "" {
+		si.Extra["os"] = match.Software.OS
+	}
+	if cpe := match.Software.CPE(); cpe != "" {
+		si.Extra["cpe"] = cpe
+	}
+	if len(match.Capabilities) > 0 {
+		si.Extra["capabilities"] = strings.Join(match.Capabilities, ",")
+	}
+	if match.Unauthenticated {
+		si.Extra["unauthenticated"] = strconv.FormatBool(true)
+	}
+	return si
+}
@@ -0,0 +1,23 @@
+package portscanner
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServiceInfoNameFromProduct(t *testing.T) {
+	port := listen(t, func(conn net.Conn) {
+		conn.Read(make([]byte, 512))
+		conn.Write([]byte("HTTP/1.1 200 OK\r\nServer: nginx/1.25.3\r\nContent-Length: 0\r\n\r\n"))
+	})
+	if KNOWN_PORTS[port] != "" {
+		t.Skipf("port %d is listed as %s", port, KNOWN_PORTS[port])
+	}
+
+	ps := NewPortScanner("127.0.0.1", time.Second, 1)
+	info := ps.DescribePortInfo(port)
+	if info.Name != "nginx" || info.Product != "nginx" || info.Version != "1.25.3" {
+		t.Fatalf("DescribePortInfo = %+v (%s), want Name and Product nginx 1.25.3", info, info)
+	}
+}
@@ -0,0 +1,58 @@
+package portscanner
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const servicesPath = "/etc/services"
+
+var systemServices struct {
+	once  sync.Once
+	ports map[int]string
+}
+
+// systemService looks port up in the operating system's service
+// database. The file is read once, on first use.
+func systemService(port int) (string, bool) {
+	systemServices.once.Do(func() {
+		f, err := os.Open(servicesPath)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		systemServices.ports = parseServices(f)
+	})
+	name, ok := systemServices.ports[port]
+	return name, ok
+}
+
+// parseServices reads services(5) entries ("name port/proto aliases...")
+// and keeps the first TCP name listed for each port.
+func parseServices(r io.Reader) map[int]string {
+	ports := map[int]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		portStr, proto, ok := strings.Cut(fields[1], "/")
+		if !ok || proto != "tcp" {
+			continue
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			continue
+		}
+		if _, seen := ports[port]; !seen {
+			ports[port] = fields[0]
+		}
+	}
+	return ports
+}
@@ -0,0 +1,124 @@
+package portscanner
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ResultSink receives the results of scans as they are produced, e.g. to
+// write them to disk or a database instead of keeping them in reports.
+type ResultSink interface {
+	Write(res ScanResult) error
+	Close() error
+}
+
+// lockedSink serializes the writes of scans running in parallel, such as
+// those of ScanHosts, so sinks need not be safe for concurrent use.
+type lockedSink struct {
+	mu   sync.Mutex
+	sink ResultSink
+}
+
+func (s *lockedSink) Write(res ScanResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sink.Write(res)
+}
+
+func (s *lockedSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sink.Close()
+}
+
+// resultWriter writes the results of a scan of host to the WithResultSink
+// sink as each one is final, with Host set. It keeps the first error;
+// once a write failed, the results that follow are dropped. It is safe
+// for concurrent use.
+type resultWriter struct {
+	sink ResultSink
+	host string
+
+	mu  sync.Mutex
+	err error
+}
+
+// newResultWriter returns the writer of a scan of host, or nil without a
+// WithResultSink sink.
+func (ps PortScanner) newResultWriter(host string) *resultWriter {
+	if ps.resultSink == nil {
+		return nil
+	}
+	return &resultWriter{sink: ps.resultSink, host: host}
+}
+
+func (w *resultWriter) write(res ScanResult) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return
+	}
+	if res.Host == "" {
+		res.Host = w.host
+	}
+	if err := w.sink.Write(res); err != nil {
+		w.err = fmt.Errorf("portscanner: result sink: %w", err)
+	}
+}
+
+// failure is the first error of the sink, or nil.
+func (w *resultWriter) failure() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// FileSink writes results to a file as JSON lines, one object per result
+// with the fields of JSONFormatter results plus the host.
+type FileSink struct {
+	f   *os.File
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewFileSink creates the file at path, truncating it if it exists, and
+// returns a sink writing to it.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("portscanner: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	return &FileSink{f: f, w: w, enc: json.NewEncoder(w)}, nil
+}
+
+func (s *FileSink) Write(res ScanResult) error {
+	return s.enc.Encode(newJSONResult(res))
+}
+
+// Close flushes what is buffered and closes the file.
+func (s *FileSink) Close() error {
+	err := s.w.Flush()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ChanSink sends results on a channel, for a consumer of its own. Writes
+// block while the channel is full, which slows the scan down to the pace
+// of the consumer. Close closes the channel.
+type ChanSink chan<- ScanResult
+
+func (s ChanSink) Write(res ScanResult) error {
+	s <- res
+	return nil
+}
+
+func (s ChanSink) Close() error {
+	close(s)
+	return nil
+}
@@ -0,0 +1,45 @@
+package portscanner
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// recordingSink notes when each result arrives.
+type recordingSink map[int]time.Time
+
+func (s recordingSink) Write(res ScanResult) error {
+	s[res.Port] = time.Now()
+	return nil
+}
+
+func (s recordingSink) Close() error {
+	return nil
+}
+
+func TestResultSinkGetsEachPortWhenDescribed(t *testing.T) {
+	fast := listen(t, func(conn net.Conn) {
+		conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+		conn.Read(make([]byte, 1))
+	})
+	slow := listen(t, func(conn net.Conn) { conn.Read(make([]byte, 1)) })
+
+	sink := recordingSink{}
+	ps := NewPortScanner("127.0.0.1", time.Second, 2)
+	if err := ps.Apply(WithResultSink(sink), WithReadTimeout(500*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	report := ps.scanPorts([]int{fast, slow})
+	done := time.Now()
+
+	if report.SinkErr != nil || len(report.Results) != 0 {
+		t.Fatalf("SinkErr = %v, Results = %v, want results in the sink only", report.SinkErr, report.Results)
+	}
+	if len(sink) != 2 {
+		t.Fatalf("sink got %d results, want 2", len(sink))
+	}
+	if ahead := done.Sub(sink[fast]); ahead < 250*time.Millisecond {
+		t.Fatalf("fast port sunk %s before the scan ended, want it as soon as described", ahead)
+	}
+}
@@ -0,0 +1,55 @@
+package portscanner
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// sourceAddr is the local address dials on network are made from, or nil
+// to let the system choose.
+func (ps PortScanner) sourceAddr(network string) net.Addr {
+	if ps.sourceIP == nil {
+		return nil
+	}
+	if strings.HasPrefix(network, ProtocolUDP) {
+		return &net.UDPAddr{IP: ps.sourceIP}
+	}
+	return &net.TCPAddr{IP: ps.sourceIP}
+}
+
+// interfaceAddr picks the address of the named interface to dial from: a
+// unicast address that is not link-local, IPv6 only when the network is
+// tcp6 and IPv4 otherwise, falling back to the other family.
+func interfaceAddr(name, network string) (net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("portscanner: interface %q: %w", name, err)
+	}
+	if iface.Flags&net.FlagUp == 0 {
+		return nil, fmt.Errorf("portscanner: interface %q is down", name)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("portscanner: interface %q: %w", name, err)
+	}
+
+	wantV6 := network == "tcp6"
+	var fallback net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() || ipNet.IP.IsMulticast() || ipNet.IP.IsUnspecified() {
+			continue
+		}
+		if (ipNet.IP.To4() == nil) == wantV6 {
+			return ipNet.IP, nil
+		}
+		if fallback == nil && network == "tcp" {
+			fallback = ipNet.IP
+		}
+	}
+	if fallback == nil {
+		return nil, fmt.Errorf("portscanner: interface %q has no usable address for %s", name, network)
+	}
+	return fallback, nil
+}
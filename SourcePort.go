@@ -0,0 +1,59 @@
+package portscanner
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+// portRotor hands out the ports of [low, high] in turn.
+type portRotor struct {
+	low, high int
+	next      atomic.Uint32
+}
+
+func (r *portRotor) pick() int {
+	span := uint32(r.high - r.low + 1)
+	return r.low + int((r.next.Add(1)-1)%span)
+}
+
+// sourcePort is the local port of the next dial, or 0 to let the system
+// choose.
+func (ps PortScanner) sourcePort() int {
+	if ps.sourcePorts == nil {
+		return 0
+	}
+	return ps.sourcePorts.pick()
+}
+
+// dialControl is the Control of every dial of the scanner: the
+// WithSocketOptions one, after SO_REUSEADDR when WithSourcePorts binds
+// dials to given ports.
+func (ps PortScanner) dialControl() func(network, address string, c syscall.RawConn) error {
+	if ps.sourcePorts == nil || !reuseAddrSupported {
+		return ps.socketOptions
+	}
+	if ps.socketOptions == nil {
+		return reuseAddrControl
+	}
+	own := ps.socketOptions
+	return func(network, address string, c syscall.RawConn) error {
+		if err := reuseAddrControl(network, address, c); err != nil {
+			return err
+		}
+		return own(network, address, c)
+	}
+}
+
+// localAddr is sourceAddr with the port of the next dial.
+func (ps PortScanner) localAddr(network string) net.Addr {
+	port := ps.sourcePort()
+	if port == 0 {
+		return ps.sourceAddr(network)
+	}
+	if strings.HasPrefix(network, ProtocolUDP) {
+		return &net.UDPAddr{IP: ps.sourceIP, Port: port}
+	}
+	return &net.TCPAddr{IP: ps.sourceIP, Port: port}
+}
@@ -0,0 +1,24 @@
+package portscanner
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reuseAddrSupported tells whether dials bound by WithSourcePorts may
+// share a source port.
+const reuseAddrSupported = true
+
+// reuseAddrControl sets SO_REUSEADDR, so that dials to different
+// destinations can be bound to the same source port at once, and to a
+// port whose earlier connection is still in TIME_WAIT.
+func reuseAddrControl(network, address string, c syscall.RawConn) error {
+	var err error
+	if ctrlErr := c.Control(func(fd uintptr) {
+		err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1)
+	}); ctrlErr != nil {
+		return ctrlErr
+	}
+	return err
+}
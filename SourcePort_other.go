@@ -0,0 +1,13 @@
+//go:build !linux
+
+package portscanner
+
+import "syscall"
+
+// reuseAddrSupported tells whether dials bound by WithSourcePorts may
+// share a source port.
+const reuseAddrSupported = false
+
+func reuseAddrControl(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,63 @@
+package portscanner
+
+import "sort"
+
+// StackRule recognises a deployed stack from the ports open on a host.
+// Each group of Requires must have at least one open port.
+type StackRule struct {
+	Name     string
+	Requires [][]int
+}
+
+// Stack is a StackRule that matched, with the open ports that made it
+// match.
+type Stack struct {
+	Name  string
+	Ports []int
+}
+
+// STACK_RULES are checked in order by InferStacks. Append to it to teach
+// the scanner about other stacks.
+var STACK_RULES = []StackRule{
+	{"LAMP stack", [][]int{{80, 443, 8080, 8443}, {3306}}},
+	{"Web application with PostgreSQL", [][]int{{80, 443, 8080, 8443}, {5432}}},
+	{"ELK stack", [][]int{{9200}, {5601}}},
+	{"Mail server", [][]int{{25, 465, 587}, {110, 143, 993, 995}}},
+	{"Active Directory domain controller", [][]int{{88}, {389, 636}, {445}}},
+	{"Kafka with Zookeeper", [][]int{{9092}, {2181}}},
+	{"Docker host", [][]int{{2375, 2376}}},
+}
+
+// InferStacks returns the STACK_RULES matched by a set of open ports.
+func InferStacks(openPorts []int) []Stack {
+	open := make(map[int]bool, len(openPorts))
+	for _, port := range openPorts {
+		open[port] = true
+	}
+
+	var stacks []Stack
+	for _, rule := range STACK_RULES {
+		if stack, ok := rule.match(open); ok {
+			stacks = append(stacks, stack)
+		}
+	}
+	return stacks
+}
+
+func (rule StackRule) match(open map[int]bool) (Stack, bool) {
+	stack := Stack{Name: rule.Name}
+	for _, group := range rule.Requires {
+		found := false
+		for _, port := range group {
+			if open[port] {
+				stack.Ports = append(stack.Ports, port)
+				found = true
+			}
+		}
+		if !found {
+			return Stack{}, false
+		}
+	}
+	sort.Ints(stack.Ports)
+	return stack, len(rule.Requires) > 0
+}
@@ -0,0 +1,165 @@
+package portscanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"time"
+)
+
+const (
+	CLOSED   = "<closed>"
+	FILTERED = "<filtered>"
+)
+
+type PortState int
+
+const (
+	PortOpen PortState = iota
+	PortClosed
+	PortFiltered
+	// PortOpenFiltered is a UDP port that did not answer: the service may
+	// be ignoring the probe or a firewall dropping it.
+	PortOpenFiltered
+)
+
+func (s PortState) String() string {
+	switch s {
+	case PortOpen:
+		return "open"
+	case PortClosed:
+		return "closed"
+	case PortFiltered:
+		return "filtered"
+	case PortOpenFiltered:
+		return "open|filtered"
+	}
+	return "unknown"
+}
+
+// IsOpenE is IsOpen returning the dial error for ports that are not open.
+// With WithOpenCache, the outcome of a recent check of the port is
+// returned without dialing again.
+func (ps PortScanner) IsOpenE(port int) (bool, error) {
+	if ps.openCache == nil {
+		return ps.isOpenContext(context.Background(), port)
+	}
+
+	key := ps.hostPort(port)
+	if result, ok := ps.openCache.get(key); ok {
+		return result.open, result.err
+	}
+	open, err := ps.isOpenContext(context.Background(), port)
+	ps.openCache.put(key, openResult{open: open, err: err})
+	return open, err
+}
+
+func (ps PortScanner) isOpenContext(ctx context.Context, port int) (bool, error) {
+	_, err := ps.probe(ctx, port)
+	return err == nil, err
+}
+
+// probe dials port once and returns the local address the connection was
+// made from. The address is nil when the port is not open, or when the
+// dial failed with an error treated as open.
+func (ps PortScanner) probe(ctx context.Context, port int) (net.Addr, error) {
+	began := time.Now()
+	conn, err := ps.dialContext(ctx, port)
+	latency := time.Since(began)
+	if err == nil {
+		// Deferred so that a panicking callback does not leak it.
+		defer ps.closeProbe(conn)
+	}
+	ps.metrics.record(latency, err)
+	if ps.onConnect != nil {
+		ps.onConnect(port, latency, err)
+	}
+	if err != nil {
+		if ps.treatedAsOpen(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return conn.LocalAddr(), nil
+}
+
+// closeProbe closes a connection opened only to test a port. With fast
+// close enabled the socket is reset instead, so it does not linger in
+// TIME_WAIT holding a local port.
+func (ps PortScanner) closeProbe(conn net.Conn) {
+	if tcpConn, ok := conn.(*net.TCPConn); ok && ps.fastClose {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+}
+
+func (ps PortScanner) treatedAsOpen(err error) bool {
+	for _, match := range ps.openErrors {
+		if match(err) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ps PortScanner) dialContext(ctx context.Context, port int) (net.Conn, error) {
+	dialer := net.Dialer{
+		Timeout:       ps.dialTimeout(port),
+		Resolver:      ps.dnsResolver,
+		LocalAddr:     ps.localAddr(ps.network),
+		Control:       ps.dialControl(),
+		FallbackDelay: ps.fallbackDelay(),
+	}
+	return dialer.DialContext(ctx, ps.network, ps.hostPort(port))
+}
+
+// resolver is the resolver set by WithResolver, or the default one.
+func (ps PortScanner) resolver() *net.Resolver {
+	if ps.dnsResolver != nil {
+		return ps.dnsResolver
+	}
+	return net.DefaultResolver
+}
+
+// dialTimeout is the timeout of the probe of port: shorter for the ports
+// WithSkipFiltered found filtered before.
+func (ps PortScanner) dialTimeout(port int) time.Duration {
+	if ps.knownFiltered[port] {
+		return ps.timeout / filteredTimeoutDivisor
+	}
+	return ps.timeout
+}
+
+// State dials port and classifies the outcome. A refused connection means
+// the port is closed; anything else that prevents connecting (timeouts,
+// unreachable networks, dropped packets) is reported as filtered.
+func (ps PortScanner) State(port int) (PortState, error) {
+	_, err := ps.IsOpenE(port)
+	return classifyDialError(err), err
+}
+
+func classifyDialError(err error) PortState {
+	if err == nil {
+		return PortOpen
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return PortClosed
+	}
+	return PortFiltered
+}
+
+// ErrHostUnreachable is reported when a scan is abandoned because no route
+// leads to the host: waiting on the other ports would only burn timeouts.
+var ErrHostUnreachable = errors.New("portscanner: host unreachable")
+
+// isUnreachable tells whether err says the host cannot be reached at
+// all, as opposed to a single port being refused or dropped.
+func isUnreachable(err error) bool {
+	return errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH)
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,126 @@
+package portscanner
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ReportStore keeps reports for later, e.g. to track over time what a host
+// exposes.
+type ReportStore interface {
+	Save(r Report) error
+}
+
+// sqlSchema is the schema SQLStore creates. Times are stored as text in
+// UTC, in a fixed-width layout so they sort as they compare.
+var sqlSchema = []string{
+	`CREATE TABLE IF NOT EXISTS scans (
+		id INTEGER PRIMARY KEY,
+		host TEXT NOT NULL,
+		started_at TEXT NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		fingerprint TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS ports (
+		scan_id INTEGER NOT NULL REFERENCES scans(id),
+		protocol TEXT NOT NULL,
+		port INTEGER NOT NULL,
+		state TEXT NOT NULL,
+		service TEXT NOT NULL,
+		cpe TEXT NOT NULL,
+		PRIMARY KEY (scan_id, protocol, port)
+	)`,
+	`CREATE INDEX IF NOT EXISTS ports_port ON ports (port, protocol)`,
+	`CREATE INDEX IF NOT EXISTS scans_host ON scans (host, started_at)`,
+}
+
+const sqlTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// SQLStore saves reports to a SQLite database, one row in scans per
+// report and one row in ports per result. The database is opened by the
+// caller with the driver of their choice, so this package depends on
+// database/sql only:
+//
+//	db, err := sql.Open("sqlite3", "scans.db")
+//	...
+//	store, err := portscanner.NewSQLStore(db)
+//	...
+//	err = store.Save(report)
+//
+// The schema relies on SQLite: an INTEGER PRIMARY KEY assigning scan ids
+// and TEXT columns in keys and indexes. Other databases are not supported.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore creates the scans and ports tables in db, unless they exist,
+// and returns a store writing to them.
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	for _, stmt := range sqlSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("portscanner: sql schema: %w", err)
+		}
+	}
+	return &SQLStore{db: db}, nil
+}
+
+// Save writes the report and its results in a single transaction.
+func (s *SQLStore) Save(r Report) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("portscanner: sql: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			err = fmt.Errorf("portscanner: sql: %w", err)
+		}
+	}()
+
+	res, err := tx.Exec(`INSERT INTO scans (host, started_at, duration_ms, fingerprint) VALUES (?, ?, ?, ?)`,
+		r.Host, r.StartedAt.UTC().Format(sqlTimeLayout), r.Duration.Milliseconds(), r.Fingerprint())
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO ports (scan_id, protocol, port, state, service, cpe) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, result := range r.Results {
+		protocol := result.Protocol
+		if protocol == "" {
+			protocol = ProtocolTCP
+		}
+		if _, err = stmt.Exec(id, protocol, result.Port, result.State.String(), result.Service, result.CPE); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// FirstSeen returns when port was first found open on host over protocol,
+// going by the start of the scans saved. ok is false when it never was.
+func (s *SQLStore) FirstSeen(host, protocol string, port int) (first time.Time, ok bool, err error) {
+	var started sql.NullString
+	err = s.db.QueryRow(`SELECT MIN(scans.started_at) FROM scans JOIN ports ON ports.scan_id = scans.id
+		WHERE scans.host = ? AND ports.protocol = ? AND ports.port = ? AND ports.state = ?`,
+		host, protocol, port, PortOpen.String()).Scan(&started)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && !started.Valid {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("portscanner: sql: %w", err)
+	}
+	if first, err = time.Parse(sqlTimeLayout, started.String); err != nil {
+		return time.Time{}, false, fmt.Errorf("portscanner: sql: %w", err)
+	}
+	return first, true, nil
+}
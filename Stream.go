@@ -0,0 +1,120 @@
+package portscanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScanStream scans [start, end] and sends a described ScanResult for each
+// open port as soon as it is known, in completion order: each open port is
+// handed from the probes to the describers as soon as it is found, and
+// sent once fingerprinted. Describing is bounded by WithDescribeConcurrency
+// rather than by the probe threads, so slow predictors do not hold back
+// detection. The channel is closed once the scan is over. The channel must
+// be drained; use ScanStreamContext to be able to stop early.
+func (ps PortScanner) ScanStream(start, end int) <-chan ScanResult {
+	return ps.stream(context.Background(), start, end, false)
+}
+
+// ScanStreamAll is ScanStream sending a ScanResult for every port of the
+// range, closed and filtered ones included, as soon as its state is
+// decided; open ports still come once described. It sends end-start+1
+// results, mostly closed ports, so consumers building a live state table
+// should keep up with that rate rather than the few open ports
+// ScanStream yields.
+func (ps PortScanner) ScanStreamAll(start, end int) <-chan ScanResult {
+	return ps.stream(context.Background(), start, end, true)
+}
+
+// ScanStreamContext is ScanStream stopping when ctx is done or cancel is
+// called, whichever comes first. Probes and descriptions in flight are
+// aborted, and the channel is closed once every worker has returned, so a
+// consumer can abandon the stream without leaking goroutines.
+func (ps PortScanner) ScanStreamContext(ctx context.Context, start, end int) (<-chan ScanResult, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	return ps.stream(ctx, start, end, false), cancel
+}
+
+// stream probes the range and emits results as they complete. Open ports
+// go through a separate pool of describers before being sent; other ports
+// are only sent, right away, when all is set.
+func (ps PortScanner) stream(ctx context.Context, start, end int, all bool) <-chan ScanResult {
+	results := make(chan ScanResult, ps.threads)
+	open := make(chan ScanResult, ps.threads)
+	send := func(res ScanResult) {
+		select {
+		case results <- res:
+		case <-ctx.Done():
+		}
+	}
+
+	var describers sync.WaitGroup
+	describers.Add(1)
+	go func() {
+		defer describers.Done()
+		limiter := newAdaptiveLimiter(ps.describeWorkers(), ps.describeSlow, ps.describeRecovered)
+		for res := range open {
+			limiter.acquire()
+			describers.Add(1)
+			go func(res ScanResult) {
+				defer describers.Done()
+				began := time.Now()
+				match, _ := ps.safeDescribe(ctx, res.Port)
+				limiter.release(time.Since(began))
+
+				res.Service, res.Software = match.Description, match.Software
+				res.CPE = match.Software.CPE()
+				res.Unauthenticated = match.Unauthenticated
+				res.Capabilities = match.Capabilities
+				send(res)
+			}(res)
+		}
+	}()
+
+	go func() {
+		ps.probePorts(ctx, ps.scanOrder(start, end), func(port int, local net.Addr, _ time.Duration, err error) {
+			res := ScanResult{Port: port, State: classifyDialError(err), Category: portCategory(port), Protocol: ProtocolTCP}
+			switch {
+			case res.State == PortOpen:
+				res.LocalAddr = local
+				select {
+				case open <- res:
+				case <-ctx.Done():
+				}
+			case all:
+				send(res)
+			}
+		})
+		close(open)
+		describers.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// WriteText scans [start, end] and writes one aligned line per open port
+// as each one is described, e.g.
+//
+//	PORT 22    open     SSH
+//
+// With WithVerbose set, closed and filtered ports are written too.
+func (ps PortScanner) WriteText(w io.Writer, start, end int) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for res := range ps.stream(ctx, start, end, ps.verbose) {
+		if _, err := fmt.Fprintln(w, formatTextLine(res)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatTextLine(res ScanResult) string {
+	return strings.TrimRight(fmt.Sprintf("PORT %-5d %-8s %s", res.Port, res.State, res.Service), " ")
+}
@@ -0,0 +1,117 @@
+package portscanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// ScanTargets checks exactly the given "host:port" targets, e.g. from an
+// earlier discovery, rather than ranges: each is dialed once and, when
+// open, described. IPv6 hosts are bracketed, as in "[::1]:22". Results
+// come in the order of the targets, whatever their state, with Host set.
+// Targets on ports WithPortFilter rejects are left out without being
+// dialed. With WithResultSink, each result is written to the sink once
+// checked instead and none are returned. Malformed targets are left out
+// and reported together in the error, along with the panics recovered
+// while probing or describing the others and the error of the sink. It
+// is nil when every target parsed and was checked.
+func (ps PortScanner) ScanTargets(targets []string) ([]ScanResult, error) {
+	type target struct {
+		host string
+		port int
+	}
+	var valid []target
+	var errs []error
+	seen := make(map[target]bool, len(targets))
+	// WithPortFilter is consulted once per port, whatever the hosts.
+	allowed := map[int]bool{}
+	for _, t := range targets {
+		host, portSpec, err := net.SplitHostPort(t)
+		if err == nil && host == "" {
+			err = fmt.Errorf("missing host in address %s", t)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("portscanner: %w", err))
+			continue
+		}
+		port, err := parsePort(portSpec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w in target %q", err, t))
+			continue
+		}
+		ok, known := allowed[port]
+		if !known {
+			ok = len(ps.filterPorts([]int{port})) > 0
+			allowed[port] = ok
+		}
+		if !ok {
+			continue
+		}
+		if tg := (target{host, port}); !seen[tg] {
+			seen[tg] = true
+			valid = append(valid, tg)
+		}
+	}
+
+	results := make([]ScanResult, len(valid))
+	var mu sync.Mutex
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, ps.threads)
+	breaker := ps.breaker()
+	sink := ps.newResultWriter("")
+	for i, tg := range valid {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, host string, port int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			var panicked *PortPanic
+			results[i], panicked = ps.forHost(host).scanTarget(port, breaker)
+			results[i].Host = host
+			if sink != nil {
+				sink.write(results[i])
+			}
+			if panicked != nil {
+				mu.Lock()
+				errs = append(errs, panicked)
+				mu.Unlock()
+			}
+		}(i, tg.host, tg.port)
+	}
+	wg.Wait()
+	if sink != nil {
+		return nil, errors.Join(append(errs, sink.failure())...)
+	}
+	return results, errors.Join(errs...)
+}
+
+// forHost is the scanner aimed at host, sharing everything else with ps
+// but the ports WithSkipFiltered found filtered on another host.
+func (ps PortScanner) forHost(host string) PortScanner {
+	if host != ps.host {
+		ps.knownFiltered = nil
+	}
+	ps.host, ps.ip = host, net.ParseIP(host)
+	return ps
+}
+
+func (ps PortScanner) scanTarget(port int, breaker *localBreaker) (ScanResult, *PortPanic) {
+	local, err := ps.safeProbe(context.Background(), port, breaker)
+	result := ScanResult{Port: port, State: classifyDialError(err), Category: portCategory(port), Protocol: ProtocolTCP}
+	var panicked *PortPanic
+	if errors.As(err, &panicked) || result.State != PortOpen {
+		return result, panicked
+	}
+
+	match, panicked := ps.safeDescribe(context.Background(), port)
+	result.Service = match.Description
+	result.Software = match.Software
+	result.CPE = match.Software.CPE()
+	result.Unauthenticated = match.Unauthenticated
+	result.LocalAddr = local
+	result.Capabilities = match.Capabilities
+	return result, panicked
+}
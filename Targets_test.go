@@ -0,0 +1,51 @@
+package portscanner
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestScanTargetsFiltersAndSinks(t *testing.T) {
+	silent := func(conn net.Conn) { conn.Read(make([]byte, 1)) }
+	kept, rejected := listen(t, silent), listen(t, silent)
+
+	var dialed []int
+	sink := recordingSink{}
+	ps := NewPortScanner("127.0.0.1", time.Second, 2)
+	err := ps.Apply(
+		WithPortFilter(func(port int) bool { return port != rejected }),
+		WithConnectCallback(func(port int, _ time.Duration, _ error) { dialed = append(dialed, port) }),
+		WithResultSink(sink),
+		WithReadTimeout(100*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := func(port int) string { return net.JoinHostPort("127.0.0.1", strconv.Itoa(port)) }
+	results, err := ps.ScanTargets([]string{target(kept), target(rejected)})
+	if err != nil || results != nil {
+		t.Fatalf("ScanTargets = %v, %v, want the results in the sink only", results, err)
+	}
+	if len(dialed) != 1 || dialed[0] != kept {
+		t.Fatalf("dialed %v, want %d only", dialed, kept)
+	}
+	if _, ok := sink[kept]; !ok || len(sink) != 1 {
+		t.Fatalf("sink got ports %v, want %d", sink, kept)
+	}
+}
+
+func TestForHostScopesSkipFiltered(t *testing.T) {
+	ps := NewPortScanner("10.0.0.1", time.Second, 1)
+	if err := ps.Apply(WithSkipFiltered(Report{Host: "10.0.0.1", Filtered: []int{8080}})); err != nil {
+		t.Fatal(err)
+	}
+	if d := ps.forHost("10.0.0.1").dialTimeout(8080); d != time.Second/filteredTimeoutDivisor {
+		t.Fatalf("dial timeout on the report's host = %s, want it shortened", d)
+	}
+	if d := ps.forHost("10.0.0.2").dialTimeout(8080); d != time.Second {
+		t.Fatalf("dial timeout on another host = %s, want the full timeout", d)
+	}
+}
@@ -0,0 +1,97 @@
+package portscanner
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"syscall"
+)
+
+const (
+	throttleWindow   = 32
+	throttleBaseline = 0.02 // weight of each outcome in the long-run rate
+)
+
+// resetThrottle lowers probe concurrency when the share of refused or
+// reset connections in the last throttleWindow probes rises more than
+// sensitivity above its long-run average, i.e. when the target starts
+// answering everything with RSTs. Concurrency is halved on every full
+// window that still looks like a storm and doubled back once it calms.
+type resetThrottle struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active int
+	limit  int
+	max    int
+
+	sensitivity float64
+	baseline    float64
+	calibrated  bool
+	window      int
+	resets      int
+
+	logger *slog.Logger
+	host   string
+}
+
+func newResetThrottle(max int, sensitivity float64, logger *slog.Logger, host string) *resetThrottle {
+	if max < 1 {
+		max = 1
+	}
+	t := &resetThrottle{limit: max, max: max, sensitivity: sensitivity, logger: logger, host: host}
+	t.cond = sync.NewCond(&t.mu)
+	return t
+}
+
+func (t *resetThrottle) acquire() {
+	t.mu.Lock()
+	for t.active >= t.limit {
+		t.cond.Wait()
+	}
+	t.active++
+	t.mu.Unlock()
+}
+
+func (t *resetThrottle) release(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.cond.Broadcast()
+
+	t.active--
+	reset := 0.0
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		reset = 1
+		t.resets++
+	}
+	if t.calibrated {
+		t.baseline += throttleBaseline * (reset - t.baseline)
+	}
+
+	t.window++
+	if t.window < throttleWindow {
+		return
+	}
+	rate := float64(t.resets) / float64(t.window)
+	t.window, t.resets = 0, 0
+	if !t.calibrated {
+		// The first window sets the baseline the following ones are
+		// compared with.
+		t.baseline, t.calibrated = rate, true
+		return
+	}
+
+	switch {
+	case rate-t.baseline > t.sensitivity && t.limit > 1:
+		t.limit = max(1, t.limit/2)
+		t.log("reset storm detected, backing off", rate)
+	case rate-t.baseline < t.sensitivity/2 && t.limit < t.max:
+		t.limit = min(t.max, t.limit*2)
+		t.log("reset storm over, recovering", rate)
+	}
+}
+
+func (t *resetThrottle) log(msg string, rate float64) {
+	if t.logger != nil {
+		t.logger.Warn(msg, "host", t.host, "reset_rate", rate, "baseline", t.baseline, "concurrency", t.limit)
+	}
+}
@@ -0,0 +1,45 @@
+package portscanner
+
+import (
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// PROTOCOL_TIMEOUTS are the least time the predictors are given for each
+// exchange with services of these protocols while describing a port, when
+// the scanner's own timeout is shorter: TLS handshakes take several round
+// trips, and SMTP servers that greylist hold their greeting back. Dials
+// keep the scanner's connect timeout. Entries can be changed or added;
+// WithReadTimeout overrides them all.
+var PROTOCOL_TIMEOUTS = map[string]time.Duration{
+	"TLS":  5 * time.Second,
+	"SMTP": 15 * time.Second,
+}
+
+// portProtocols are the protocols of PROTOCOL_TIMEOUTS known to be spoken
+// on a port before talking to it.
+var portProtocols = map[int]string{
+	25:   "SMTP",
+	443:  "TLS",
+	465:  "SMTP",
+	587:  "SMTP",
+	636:  "TLS",
+	993:  "TLS",
+	995:  "TLS",
+	2376: "TLS",
+	8443: "TLS",
+	8883: "TLS",
+}
+
+// applyProtocolTimeouts lengthens the timeouts of the session describing
+// port as PROTOCOL_TIMEOUTS says, unless WithReadTimeout is set.
+func (ps PortScanner) applyProtocolTimeouts(session *predictors.Session, port int) {
+	if ps.readTimeout > 0 {
+		return
+	}
+	if timeout := PROTOCOL_TIMEOUTS[portProtocols[port]]; timeout > session.Timeout {
+		session.Timeout = timeout
+	}
+	session.HandshakeTimeout = PROTOCOL_TIMEOUTS["TLS"]
+}
@@ -0,0 +1,201 @@
+package portscanner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+)
+
+// udpPayloads are the datagrams sent to ports whose service ignores an
+// empty one: a DNS query for version.bind, an NTP client request and an
+// SNMPv1 get of sysDescr with the "public" community.
+var udpPayloads = map[int][]byte{
+	53: {
+		0x12, 0x34, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x07, 'v', 'e', 'r', 's', 'i', 'o', 'n', 0x04, 'b', 'i', 'n', 'd', 0x00,
+		0x00, 0x10, 0x00, 0x03,
+	},
+	123: append([]byte{0x1b}, make([]byte, 47)...),
+	161: {
+		0x30, 0x29, 0x02, 0x01, 0x00, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c',
+		0xa0, 0x1c, 0x02, 0x04, 0x12, 0x34, 0x56, 0x78, 0x02, 0x01, 0x00, 0x02, 0x01, 0x00,
+		0x30, 0x0e, 0x30, 0x0c, 0x06, 0x08, 0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00, 0x05, 0x00,
+	},
+}
+
+// ScanCombined scans [start, end] over both TCP and UDP and merges the
+// results, ordered by port then protocol, each tagged with its Protocol.
+// UDP ports that answered are open; those that neither answered nor were
+// reported unreachable are PortOpenFiltered, as silence is what both an
+// open service ignoring the probe and a firewall look like.
+//
+// The two scans run side by side within the threads of the scanner: a
+// quarter of them, at least one, probe UDP and the rest TCP, so slow UDP
+// timeouts do not starve the TCP scan. A scanner with a single thread
+// scans TCP first, then UDP. The other fields of the report describe the
+// TCP scan.
+func (ps PortScanner) ScanCombined(start, end int) Report {
+	var udp []ScanResult
+	sink := ps.newResultWriter(ps.host)
+	scanUDP := func(threads int) {
+		udp = ps.scanUDP(context.Background(), ps.scanOrder(start, end), threads, sink)
+	}
+
+	var report Report
+	if ps.threads < 2 {
+		report = ps.Scan(start, end)
+		scanUDP(1)
+	} else {
+		udpThreads := max(1, ps.threads/4)
+		tcp := ps
+		tcp.threads = ps.threads - udpThreads
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			scanUDP(udpThreads)
+		}()
+		report = tcp.Scan(start, end)
+		<-done
+	}
+
+	if sink != nil {
+		if report.SinkErr == nil {
+			report.SinkErr = sink.failure()
+		}
+		udp = nil
+	}
+	report.Results = append(report.Results, udp...)
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].Port < report.Results[j].Port
+	})
+	report.Duration = time.Since(report.StartedAt)
+	return report
+}
+
+// scanUDP probes ports over UDP with up to threads probes at once and
+// returns the ports that are open or open|filtered, ordered by port. Each
+// is also written to sink, if not nil, as soon as it is known.
+func (ps PortScanner) scanUDP(ctx context.Context, ports []int, threads int, sink *resultWriter) []ScanResult {
+	var results []ScanResult
+	var mu sync.Mutex
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, threads)
+
+	for _, port := range ps.filterPorts(ports) {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(port int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			state, _ := ps.probeUDP(ctx, port)
+			if state != PortOpen && state != PortOpenFiltered {
+				return
+			}
+			res := ScanResult{Port: port, State: state, Protocol: ProtocolUDP, Category: portCategory(port)}
+			if state == PortOpen {
+				match := ps.describeUDP(ctx, port)
+				res.Service, res.Software = match.Description, match.Software
+				res.CPE = match.Software.CPE()
+			}
+			if sink != nil {
+				sink.write(res)
+			}
+			mu.Lock()
+			results = append(results, res)
+			mu.Unlock()
+		}(port)
+	}
+
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].Port < results[j].Port })
+	return results
+}
+
+// probeUDP sends one datagram to port and waits up to the timeout for an
+// answer. An ICMP port unreachable surfaces as a refused read and means
+// the port is closed.
+func (ps PortScanner) probeUDP(ctx context.Context, port int) (PortState, error) {
+	dialer := net.Dialer{
+		Timeout:   ps.timeout,
+		Resolver:  ps.dnsResolver,
+		LocalAddr: ps.localAddr(ps.udpNetwork()),
+		Control:   ps.dialControl(),
+	}
+	conn, err := dialer.DialContext(ctx, ps.udpNetwork(), ps.hostPort(port))
+	if err != nil {
+		return PortFiltered, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(ps.timeout))
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if _, err := conn.Write(udpPayloads[port]); err != nil {
+		return classifyUDPError(err), err
+	}
+	// Only the fact that something came back matters.
+	buf := make([]byte, 1)
+	if _, err := conn.Read(buf); err != nil {
+		return classifyUDPError(err), err
+	}
+	return PortOpen, nil
+}
+
+// describeUDP runs the UDP predictors of port, falling back to its label
+// as the TCP path does.
+func (ps PortScanner) describeUDP(ctx context.Context, port int) predictors.Match {
+	if ps.usePredictor {
+		session := ps.newSession(ps.hostPort(port))
+		session.Network = ps.udpNetwork()
+		session.Context = ctx
+		defer session.Close()
+		if match := ps.bestMatch(session, ps.udpPredictors(port)); match.Found() {
+			return match
+		}
+	}
+	return predictors.Certain(ps.predictPort(port))
+}
+
+// udpPredictors returns the registered UDP predictors of port.
+func (ps PortScanner) udpPredictors(port int) []predictors.Predictor {
+	var matched []predictors.Predictor
+	for _, predictor := range ps.predictors {
+		if up, ok := predictor.(predictors.UDPPredictor); ok && slices.Contains(up.UDPPorts(), port) {
+			matched = append(matched, predictor)
+		}
+	}
+	return matched
+}
+
+func isUDPPredictor(predictor predictors.Predictor) bool {
+	_, ok := predictor.(predictors.UDPPredictor)
+	return ok
+}
+
+func classifyUDPError(err error) PortState {
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return PortClosed
+	case isTimeout(err):
+		return PortOpenFiltered
+	}
+	return PortFiltered
+}
+
+// udpNetwork is the UDP counterpart of the network set by WithNetwork.
+func (ps PortScanner) udpNetwork() string {
+	return strings.Replace(ps.network, ProtocolTCP, ProtocolUDP, 1)
+}
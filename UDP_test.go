@@ -0,0 +1,19 @@
+package portscanner
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDescribeUDPFallsBackLikeTCP(t *testing.T) {
+	ps := NewPortScanner("127.0.0.1", time.Second, 1)
+	for _, port := range []int{40001, 53} {
+		if got, want := ps.describeUDP(context.Background(), port).Description, ps.predictPort(port); got != want {
+			t.Errorf("describeUDP(%d) = %q, want %q", port, got, want)
+		}
+	}
+	if got := ps.describeUDP(context.Background(), 40001).Description; got != UNKNOWN {
+		t.Errorf("unlisted port described as %q, want %s", got, UNKNOWN)
+	}
+}
@@ -1,8 +1,10 @@
-module port-scanner
+module github.com/elchemista/port-scanner
 
 go 1.23.2
 
 require (
-	github.com/anvie/port-scanner v0.0.0-20180225151059-8159197d3770
-	github.com/elchemista/port-scanner v0.0.0-20180225151059-8159197d3770
+	golang.org/x/net v0.38.0
+	golang.org/x/sys v0.31.0
 )
+
+require golang.org/x/text v0.23.0 // indirect
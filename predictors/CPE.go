@@ -0,0 +1,78 @@
+package predictors
+
+import "strings"
+
+// CPE_PRODUCTS maps product names, as found in Software.Product and
+// compared case-insensitively, to the vendor and product parts of their
+// CPE 2.3 names.
+var CPE_PRODUCTS = map[string]string{
+	"apache":        "apache:http_server",
+	"nginx":         "nginx:nginx",
+	"lighttpd":      "lighttpd:lighttpd",
+	"microsoft-iis": "microsoft:internet_information_services",
+	"openssh":       "openbsd:openssh",
+	"dropbear":      "dropbear_ssh_project:dropbear_ssh",
+	"redis":         "redis:redis",
+	"mongodb":       "mongodb:mongodb",
+	"influxdb":      "influxdata:influxdb",
+	"zookeeper":     "apache:zookeeper",
+	"docker":        "docker:docker",
+	"prometheus":    "prometheus:prometheus",
+	"node_exporter": "prometheus:node_exporter",
+	"ntpd":          "ntp:ntp",
+	"jenkins":       "jenkins:jenkins",
+	"gitlab":        "gitlab:gitlab",
+	"gitea":         "gitea:gitea",
+	"teamcity":      "jetbrains:teamcity",
+}
+
+// CPE returns the CPE 2.3 name of the software, e.g.
+// "cpe:2.3:a:nginx:nginx:1.24.0:*:*:*:*:*:*:*", or "" unless both the
+// product, listed in CPE_PRODUCTS, and the version are known: a name
+// without a version matches every CVE ever filed against the product.
+func (sw Software) CPE() string {
+	product, ok := CPE_PRODUCTS[strings.ToLower(sw.Product)]
+	if !ok || sw.Version == "" {
+		return ""
+	}
+	version, update := escapeCPE(sw.Version), "*"
+	if product == "openbsd:openssh" {
+		// NVD files the portable patch level as the update: 9.6p1 is
+		// version 9.6, update p1.
+		if v, p, found := strings.Cut(sw.Version, "p"); found {
+			version, update = escapeCPE(v), escapeCPE("p"+p)
+		}
+	}
+	return "cpe:2.3:a:" + product + ":" + version + ":" + update + ":*:*:*:*:*:*"
+}
+
+// escapeCPE quotes the characters CPE 2.3 formatted strings reserve.
+func escapeCPE(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' || r == '-') {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ParseSSHBanner parses the identification string of an SSH server, so
+// "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13" yields OpenSSH, 9.6p1 and
+// Ubuntu.
+func ParseSSHBanner(banner string) Software {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(banner), "SSH-")
+	if !ok {
+		return Software{}
+	}
+	_, rest, _ = strings.Cut(rest, "-")
+	software, comment, _ := strings.Cut(rest, " ")
+
+	var sw Software
+	sw.Product, sw.Version, _ = strings.Cut(software, "_")
+	if os, _, _ := strings.Cut(comment, "-"); os != "" {
+		sw.OS = os
+	}
+	return sw
+}
@@ -0,0 +1,24 @@
+package predictors
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// FastOpenSupported tells whether Session.FastOpen has any effect on this
+// platform.
+const FastOpenSupported = true
+
+// fastOpenControl enables TCP_FASTOPEN_CONNECT on the socket: once the
+// kernel holds a cookie for the server, connect returns at once and the
+// SYN leaves with the first write.
+func fastOpenControl(network, address string, c syscall.RawConn) error {
+	var err error
+	if ctrlErr := c.Control(func(fd uintptr) {
+		err = unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN_CONNECT, 1)
+	}); ctrlErr != nil {
+		return ctrlErr
+	}
+	return err
+}
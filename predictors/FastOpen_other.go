@@ -0,0 +1,13 @@
+//go:build !linux
+
+package predictors
+
+import "syscall"
+
+// FastOpenSupported tells whether Session.FastOpen has any effect on this
+// platform.
+const FastOpenSupported = false
+
+func fastOpenControl(network, address string, c syscall.RawConn) error {
+	return nil
+}
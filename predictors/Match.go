@@ -0,0 +1,39 @@
+package predictors
+
+// Match is a predictor's answer together with how sure it is about it,
+// from 0 (no idea) to 1 (certain).
+type Match struct {
+	Description string
+	Confidence  float64
+	// Software holds the structured product and version behind
+	// Description, when the predictor could parse them.
+	Software Software
+	// Unauthenticated is set when the service ran a command without
+	// asking for credentials first.
+	Unauthenticated bool
+	// Capabilities lists what the service advertises it supports, as
+	// reported by the service, e.g. the EHLO extensions of an SMTP server.
+	Capabilities []string
+	// Source tells what the description comes from, as set by the
+	// scanner, e.g. "predictor" or "known-ports".
+	Source string
+}
+
+// MatchPredictor is implemented by predictors that can say how confident
+// they are. When several predictors match the same port, the most
+// confident one wins.
+type MatchPredictor interface {
+	PredictMatch(s *Session) Match
+}
+
+// Certain wraps the result of a predictor that does not report confidence.
+func Certain(description string) Match {
+	if description == "" {
+		return Match{}
+	}
+	return Match{Description: description, Confidence: 1}
+}
+
+func (m Match) Found() bool {
+	return m.Description != ""
+}
@@ -0,0 +1,80 @@
+package predictors
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+)
+
+// poolIdleTimeout bounds how long an idle connection is kept. Servers
+// commonly close idle keep-alive connections after a few seconds anyway.
+const poolIdleTimeout = 5 * time.Second
+
+// ConnPool keeps the idle keep-alive connections of finished sessions so
+// the next session to the same address can skip the TCP and TLS
+// handshakes. Only the connection a Session shares for HTTP is pooled:
+// Dial and DialTLS always return fresh connections, as most other
+// protocols cannot be resumed mid-conversation.
+type ConnPool struct {
+	size int
+
+	mu    sync.Mutex
+	idle  map[string][]pooledConn
+	count int
+}
+
+type pooledConn struct {
+	conn     net.Conn
+	tlsState *tls.ConnectionState
+	since    time.Time
+}
+
+// NewConnPool returns a pool holding at most size idle connections.
+func NewConnPool(size int) *ConnPool {
+	return &ConnPool{size: size, idle: map[string][]pooledConn{}}
+}
+
+func (p *ConnPool) get(key string) (pooledConn, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for conns := p.idle[key]; len(conns) > 0; conns = p.idle[key] {
+		pc := conns[len(conns)-1]
+		p.idle[key] = conns[:len(conns)-1]
+		p.count--
+		if time.Since(pc.since) < poolIdleTimeout {
+			return pc, true
+		}
+		pc.conn.Close()
+	}
+	return pooledConn{}, false
+}
+
+func (p *ConnPool) put(key string, conn net.Conn, tlsState *tls.ConnectionState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.count >= p.size {
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	p.idle[key] = append(p.idle[key], pooledConn{conn: conn, tlsState: tlsState, since: time.Now()})
+	p.count++
+}
+
+// Close closes every idle connection.
+func (p *ConnPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, conns := range p.idle {
+		for _, pc := range conns {
+			pc.conn.Close()
+		}
+		delete(p.idle, key)
+	}
+	p.count = 0
+	return nil
+}
@@ -15,6 +15,32 @@ type DetailPredictor interface {
 	PredictResponseDetail(resp string) string
 }
 
+// PortPredictor is implemented by predictors that only make sense on some
+// ports. They are consulted for those ports only, even when the port has a
+// KNOWN_PORTS label.
+type PortPredictor interface {
+	Ports() []int
+}
+
+// UDPPredictor is implemented by predictors of UDP services. They are only
+// consulted, over UDP, for the ports they list, and never over TCP.
+type UDPPredictor interface {
+	UDPPorts() []int
+}
+
+// BasePredictor provides the response hooks for predictors that do not
+// speak HTTP.
+type BasePredictor struct {
+}
+
+func (pa *BasePredictor) PredictResponse(resp string, dp DetailPredictor) string {
+	return dp.PredictResponseDetail(resp)
+}
+
+func (pa *BasePredictor) PredictResponseDetail(resp string) string {
+	return ""
+}
+
 type BaseHttpPredictor struct {
 //	DetailPredictor
 }
@@ -0,0 +1,580 @@
+package predictors
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Session holds the state shared by every predictor probing one host:port.
+// The connection it keeps is reused across HTTP exchanges as long as the
+// server allows keep-alive, and responses are cached so several predictors
+// looking at the same request trigger a single round trip.
+type Session struct {
+	Host    string
+	Network string
+	Timeout time.Duration
+	// ConnectTimeout bounds each dial; Timeout then bounds the exchanges
+	// on the connection. When zero, Timeout bounds dials too.
+	ConnectTimeout time.Duration
+
+	// TLSConfig is used for every TLS handshake of the session. When nil,
+	// certificates are not verified: the goal is to fingerprint the
+	// service, not to trust it. ServerName defaults to the host.
+	TLSConfig *tls.Config
+	// TLS makes the shared connection used by HTTP a TLS one.
+	TLS bool
+	// MaxResponseBytes caps how much is read from the service for any
+	// single response, so a hostile target cannot exhaust memory by
+	// streaming forever.
+	MaxResponseBytes int
+	// UserAgent is sent with every HTTP request.
+	UserAgent string
+	// Authorization, when set, is sent as the Authorization header of
+	// every HTTP request.
+	Authorization string
+	// Resolver resolves Host when dialing. When nil, net.DefaultResolver
+	// is used.
+	Resolver *net.Resolver
+	// SourceIP, when set, is the local address every dial is made from.
+	SourceIP net.IP
+	// SourcePort, when set, picks the local port of each dial.
+	SourcePort func() int
+	// GreetingBase, when set, makes the wait for a server greeting adapt
+	// to the network: GreetingBase plus greetingLatencyFactor times the
+	// time the connection took to establish, within Timeout. A LAN server
+	// is then given milliseconds rather than the full Timeout.
+	GreetingBase time.Duration
+	// Control, when set, is called on every socket of the session before
+	// it connects, as net.Dialer.Control is.
+	Control func(network, address string, c syscall.RawConn) error
+	// Context bounds the whole session: once it is done, dials fail and
+	// reads in progress on the session's connections return at once.
+	// When nil, only Timeout applies.
+	Context context.Context
+	// FastOpen uses TCP Fast Open for the shared HTTP connection, saving a
+	// round trip on every connection after the first to the same server.
+	// Only Linux supports it, see FastOpenSupported. Connections that wait
+	// for the server to speak first never use it.
+	FastOpen bool
+	// Pool, when set, lends the shared HTTP connection an idle one left by
+	// an earlier session to the same address, and takes it back on Close.
+	Pool *ConnPool
+	// FallbackDelay is the head start of the first address family when
+	// dialing a dual-stack host, as net.Dialer.FallbackDelay: zero leaves
+	// the net package default, negative dials one family only.
+	FallbackDelay time.Duration
+	// HandshakeTimeout, when longer than Timeout, bounds TLS handshakes
+	// instead, as they take more round trips than other exchanges.
+	HandshakeTimeout time.Duration
+
+	conn     net.Conn
+	unwatch  func() bool
+	pooled   bool
+	noPool   bool
+	budget   *budgetReader
+	reader   *bufio.Reader
+	http     map[string]string
+	banner   *string
+	tlsState *tls.ConnectionState
+
+	dialLatency time.Duration
+}
+
+const (
+	DefaultMaxResponseBytes = 4096
+	DefaultUserAgent        = "Mozilla/5.0 (compatible; port-scanner)"
+)
+
+// SessionPredictor is implemented by predictors able to probe through a
+// shared Session instead of dialing the host themselves.
+type SessionPredictor interface {
+	PredictSession(s *Session) string
+}
+
+func NewSession(host string, timeout time.Duration) *Session {
+	return &Session{
+		Host:    host,
+		Network: "tcp",
+		Timeout: timeout,
+
+		MaxResponseBytes: DefaultMaxResponseBytes,
+		UserAgent:        DefaultUserAgent,
+
+		http: map[string]string{},
+	}
+}
+
+// Dial opens a fresh connection that the caller owns.
+func (s *Session) Dial() (net.Conn, error) {
+	return s.dial(nil)
+}
+
+func (s *Session) dial(control func(network, address string, c syscall.RawConn) error) (net.Conn, error) {
+	if s.Control != nil {
+		if control == nil {
+			control = s.Control
+		} else {
+			own := control
+			control = func(network, address string, c syscall.RawConn) error {
+				if err := s.Control(network, address, c); err != nil {
+					return err
+				}
+				return own(network, address, c)
+			}
+		}
+	}
+	dialer := net.Dialer{Timeout: s.connectTimeout(), Resolver: s.Resolver, Control: control, LocalAddr: s.localAddr(), FallbackDelay: s.FallbackDelay}
+	began := time.Now()
+	conn, err := dialer.DialContext(s.context(), s.Network, s.Host)
+	if err != nil {
+		return nil, err
+	}
+	s.dialLatency = time.Since(began)
+	return &watchedConn{Conn: conn, stop: s.watch(conn)}, nil
+}
+
+// watchedConn is a connection dialed by the session, whose deadline
+// follows Context until it is closed.
+type watchedConn struct {
+	net.Conn
+	stop func() bool
+}
+
+func (c *watchedConn) Close() error {
+	c.stop()
+	return c.Conn.Close()
+}
+
+// stopWatching stops the watch dial set on conn, possibly under TLS. It
+// reports false if Context already expired the deadline of conn.
+func stopWatching(conn net.Conn) bool {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	if wc, ok := conn.(*watchedConn); ok {
+		return wc.stop()
+	}
+	return true
+}
+
+// greetingLatencyFactor is how many connection setup times a greeting may
+// take to arrive once the connection is up, see GreetingBase.
+const greetingLatencyFactor = 4
+
+// AwaitGreeting shortens the read deadline of conn, just dialed, for the
+// wait on the server's greeting as GreetingBase says. The returned
+// function restores the deadline of the session for the exchanges that
+// follow. Without GreetingBase it does nothing.
+func (s *Session) AwaitGreeting(conn net.Conn) (restore func()) {
+	if s.GreetingBase <= 0 {
+		return func() {}
+	}
+	deadline := time.Now().Add(s.GreetingBase + greetingLatencyFactor*s.dialLatency)
+	if d := s.Deadline(); d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+	return func() {
+		if s.context().Err() == nil {
+			conn.SetReadDeadline(s.Deadline())
+		}
+	}
+}
+
+func (s *Session) localAddr() net.Addr {
+	switch {
+	case s.SourcePort != nil:
+		port := s.SourcePort()
+		if strings.HasPrefix(s.Network, "udp") {
+			return &net.UDPAddr{IP: s.SourceIP, Port: port}
+		}
+		return &net.TCPAddr{IP: s.SourceIP, Port: port}
+	case s.SourceIP == nil:
+		return nil
+	case strings.HasPrefix(s.Network, "udp"):
+		return &net.UDPAddr{IP: s.SourceIP}
+	}
+	return &net.TCPAddr{IP: s.SourceIP}
+}
+
+func (s *Session) connectTimeout() time.Duration {
+	if s.ConnectTimeout > 0 {
+		return s.ConnectTimeout
+	}
+	return s.Timeout
+}
+
+// Deadline is the time by which an exchange starting now must be over:
+// Timeout from now, or earlier if Context says so.
+func (s *Session) Deadline() time.Time {
+	return s.deadline(s.Timeout)
+}
+
+func (s *Session) deadline(timeout time.Duration) time.Time {
+	deadline := time.Now().Add(timeout)
+	if d, ok := s.context().Deadline(); ok && d.Before(deadline) {
+		return d
+	}
+	return deadline
+}
+
+func (s *Session) context() context.Context {
+	if s.Context == nil {
+		return context.Background()
+	}
+	return s.Context
+}
+
+// watch sets the deadline of conn and makes it expire as soon as Context
+// is done, interrupting any read in progress. The returned function stops
+// watching.
+func (s *Session) watch(conn net.Conn) (stop func() bool) {
+	conn.SetDeadline(s.Deadline())
+	if s.Context == nil {
+		return func() bool { return true }
+	}
+	return context.AfterFunc(s.Context, func() { conn.SetDeadline(time.Now()) })
+}
+
+// DialTLS opens a fresh connection and completes a TLS handshake on it.
+func (s *Session) DialTLS() (net.Conn, error) {
+	return s.dialTLS(nil)
+}
+
+func (s *Session) dialTLS(control func(network, address string, c syscall.RawConn) error) (net.Conn, error) {
+	conn, err := s.dial(control)
+	if err != nil {
+		return nil, err
+	}
+	if s.HandshakeTimeout > s.Timeout {
+		conn.SetDeadline(s.deadline(s.HandshakeTimeout))
+		defer conn.SetDeadline(s.Deadline())
+	}
+	tlsConn := tls.Client(conn, s.tlsConfig())
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+func (s *Session) tlsConfig() *tls.Config {
+	var cfg *tls.Config
+	if s.TLSConfig != nil {
+		cfg = s.TLSConfig.Clone()
+	} else {
+		cfg = &tls.Config{InsecureSkipVerify: true}
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName, _, _ = net.SplitHostPort(s.Host)
+	}
+	return cfg
+}
+
+// TLSState returns the handshake details of the last TLS connection the
+// session shared for HTTP.
+func (s *Session) TLSState() (tls.ConnectionState, bool) {
+	if s.tlsState == nil {
+		return tls.ConnectionState{}, false
+	}
+	return *s.tlsState, true
+}
+
+// Banner returns whatever the service sends on its own right after
+// accepting a connection, such as an SSH or SMTP greeting. It reads once,
+// on a fresh connection, and caches the result. Services that wait for the
+// client to speak first yield an empty banner after Timeout.
+func (s *Session) Banner() (string, error) {
+	if s.banner != nil {
+		return *s.banner, nil
+	}
+
+	conn, err := s.Dial()
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	s.AwaitGreeting(conn)
+
+	buf := make([]byte, s.maxBytes())
+	n, err := s.Limit(conn).Read(buf)
+	if err != nil && n == 0 {
+		if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+			return "", err
+		}
+	}
+	banner := string(buf[:n])
+	s.banner = &banner
+	return banner, nil
+}
+
+// Evidence returns what the service already sent during the session,
+// without sending anything: the greeting when it was read, or else the
+// response to the first HTTP request the predictors made.
+func (s *Session) Evidence() string {
+	if s.banner != nil && *s.banner != "" {
+		return *s.banner
+	}
+	for _, key := range []string{"HEAD /", "GET /"} {
+		if resp, ok := s.http[key]; ok {
+			return resp
+		}
+	}
+	keys := make([]string, 0, len(s.http))
+	for key := range s.http {
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	slices.Sort(keys)
+	return s.http[keys[0]]
+}
+
+// Limit caps r at MaxResponseBytes. Predictors should read every service
+// response through it.
+func (s *Session) Limit(r io.Reader) io.Reader {
+	return io.LimitReader(r, s.maxBytes())
+}
+
+func (s *Session) maxBytes() int64 {
+	if s.MaxResponseBytes <= 0 {
+		return DefaultMaxResponseBytes
+	}
+	return int64(s.MaxResponseBytes)
+}
+
+// budgetReader is a LimitReader whose budget is refilled before every
+// HTTP exchange on the shared connection.
+type budgetReader struct {
+	r io.Reader
+	n int64
+}
+
+func (b *budgetReader) Read(p []byte) (int, error) {
+	if b.n <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.n {
+		p = p[:b.n]
+	}
+	n, err := b.r.Read(p)
+	b.n -= int64(n)
+	return n, err
+}
+
+// Port returns the port part of Host, or 0 if it cannot be parsed.
+func (s *Session) Port() int {
+	_, portStr, err := net.SplitHostPort(s.Host)
+	if err != nil {
+		return 0
+	}
+	port, _ := strconv.Atoi(portStr)
+	return port
+}
+
+func (s *Session) sharedConn() (net.Conn, *bufio.Reader, error) {
+	if s.conn == nil {
+		if pc, ok := s.borrow(); ok {
+			s.conn, s.tlsState, s.pooled = pc.conn, pc.tlsState, true
+			s.unwatch = s.watch(s.conn)
+		} else {
+			// HTTP and TLS clients speak first, which Fast Open needs.
+			var control func(network, address string, c syscall.RawConn) error
+			if s.FastOpen {
+				control = fastOpenControl
+			}
+			dial := s.dial
+			if s.TLS {
+				dial = s.dialTLS
+			}
+			conn, err := dial(control)
+			if err != nil {
+				return nil, nil, err
+			}
+			s.conn, s.pooled = conn, false
+			s.unwatch = func() bool { return stopWatching(conn) }
+			if tlsConn, ok := conn.(*tls.Conn); ok {
+				state := tlsConn.ConnectionState()
+				s.tlsState = &state
+			}
+		}
+		s.budget = &budgetReader{r: s.conn}
+		s.reader = bufio.NewReader(s.budget)
+	}
+	s.conn.SetDeadline(s.Deadline())
+	s.budget.n = s.maxBytes()
+	return s.conn, s.reader, nil
+}
+
+func (s *Session) poolKey() string {
+	if s.TLS {
+		return s.Network + " tls " + s.Host
+	}
+	return s.Network + " " + s.Host
+}
+
+func (s *Session) borrow() (pooledConn, bool) {
+	if s.Pool == nil || s.noPool {
+		return pooledConn{}, false
+	}
+	return s.Pool.get(s.poolKey())
+}
+
+func (s *Session) dropConn() {
+	if s.conn != nil {
+		s.unwatch()
+		s.conn.Close()
+		s.conn = nil
+		s.budget = nil
+		s.reader = nil
+		s.pooled = false
+	}
+}
+
+// HTTP sends method path over the shared connection and returns the raw
+// status line and headers of the response. Results are cached per
+// method and path for the lifetime of the session.
+func (s *Session) HTTP(method, path string) (string, error) {
+	key := method + " " + path
+	if resp, ok := s.http[key]; ok {
+		return resp, nil
+	}
+
+	reused := s.conn != nil
+	resp, err := s.roundTrip(method, path)
+	if err != nil && (reused || s.pooled) {
+		// The server may have closed an idle keep-alive connection;
+		// retry once on a fresh one.
+		s.dropConn()
+		s.noPool = true
+		resp, err = s.roundTrip(method, path)
+		s.noPool = false
+	}
+	if err != nil {
+		s.dropConn()
+		return "", err
+	}
+	s.http[key] = resp
+	return resp, nil
+}
+
+// HTTPResponse is a response read by Get: the raw status line and headers,
+// as HTTP returns them, and the body, as much of it as MaxResponseBytes
+// allows.
+type HTTPResponse struct {
+	StatusCode int
+	Header     string
+	Body       string
+}
+
+// Get sends GET path on a fresh connection, over TLS when TLS is set, and
+// returns the response along with its body. Unlike HTTP, the response is
+// not cached.
+func (s *Session) Get(path string) (HTTPResponse, error) {
+	if s.TLS {
+		return s.GetTLS(path)
+	}
+	return s.get(s.Dial, path)
+}
+
+// GetTLS is Get over TLS whatever TLS says, for services that only speak
+// TLS on some ports.
+func (s *Session) GetTLS(path string) (HTTPResponse, error) {
+	return s.get(s.DialTLS, path)
+}
+
+func (s *Session) get(dial func() (net.Conn, error), path string) (HTTPResponse, error) {
+	conn, err := dial()
+	if err != nil {
+		return HTTPResponse{}, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(s.request("GET", path, "close"))); err != nil {
+		return HTTPResponse{}, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(s.Limit(conn)), &http.Request{Method: "GET"})
+	if err != nil {
+		return HTTPResponse{}, err
+	}
+	defer resp.Body.Close()
+	dump, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		return HTTPResponse{}, err
+	}
+	// A body cut short by the limit is still worth looking at.
+	body, _ := io.ReadAll(resp.Body)
+	return HTTPResponse{StatusCode: resp.StatusCode, Header: string(dump), Body: string(body)}, nil
+}
+
+// request builds the request line and headers of method path, with the
+// given Connection header.
+func (s *Session) request(method, path, connection string) string {
+	req := fmt.Sprintf("%s %s HTTP/1.1\r\nHost: %s\r\n", method, path, s.Host)
+	if s.UserAgent != "" {
+		req += "User-Agent: " + s.UserAgent + "\r\n"
+	}
+	if s.Authorization != "" {
+		req += "Authorization: " + s.Authorization + "\r\n"
+	}
+	return req + "Connection: " + connection + "\r\n\r\n"
+}
+
+// ResetHTTP drops the shared connection and the cached HTTP responses, so
+// that the next request is sent afresh, e.g. once TLS has been switched.
+func (s *Session) ResetHTTP() {
+	s.dropConn()
+	s.tlsState = nil
+	s.http = map[string]string{}
+}
+
+func (s *Session) roundTrip(method, path string) (string, error) {
+	conn, reader, err := s.sharedConn()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := conn.Write([]byte(s.request(method, path, "keep-alive"))); err != nil {
+		return "", err
+	}
+
+	resp, err := http.ReadResponse(reader, &http.Request{Method: method})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	dump, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil || resp.Close || s.budget.n <= 0 {
+		s.dropConn()
+	}
+	return string(dump), nil
+}
+
+// Close releases the shared connection, handing it to Pool when it is
+// still usable, no longer watching Context. It is safe to call more than
+// once.
+func (s *Session) Close() error {
+	if s.Pool != nil && s.conn != nil && s.reader.Buffered() == 0 && s.unwatch() {
+		s.Pool.put(s.poolKey(), s.conn, s.tlsState)
+		s.conn, s.budget, s.reader = nil, nil, nil
+		return nil
+	}
+	s.dropConn()
+	return nil
+}
@@ -0,0 +1,88 @@
+package predictors
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestPooledConnOutlivesContext checks that a connection handed to the
+// pool no longer follows the Context of the session that dialed it.
+func TestPooledConnOutlivesContext(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					req, err := http.ReadRequest(r)
+					if err != nil {
+						return
+					}
+					req.Body.Close()
+					time.Sleep(100 * time.Millisecond)
+					conn.Write([]byte("HTTP/1.1 204 No Content\r\n\r\n"))
+				}
+			}()
+		}
+	}()
+
+	pool := NewConnPool(1)
+	defer pool.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	first := NewSession(ln.Addr().String(), time.Second)
+	first.Pool, first.Context = pool, ctx
+	if _, err := first.HTTP("HEAD", "/"); err != nil {
+		t.Fatal(err)
+	}
+	first.Close()
+
+	second := NewSession(ln.Addr().String(), time.Second)
+	second.Pool = pool
+	defer second.Close()
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, err := second.HTTP("HEAD", "/"); err != nil {
+		t.Fatal(err)
+	}
+	if n := accepted.Load(); n != 1 {
+		t.Fatalf("server accepted %d connections, want the pooled one reused", n)
+	}
+}
+
+// TestGetFollowsTLS checks that Get reads status, headers and body over
+// TLS when the session is a TLS one.
+func TestGetFollowsTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	s := NewSession(strings.TrimPrefix(srv.URL, "https://"), time.Second)
+	defer s.Close()
+	s.TLS = true
+	resp, err := s.Get("/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || HeaderValue(resp.Header, "X-Path") != "/status" || resp.Body != "hello" {
+		t.Fatalf("Get = %+v", resp)
+	}
+}
@@ -0,0 +1,65 @@
+package predictors
+
+import (
+	"net/textproto"
+	"strings"
+)
+
+// Software is a product identified on a port, as parsed from a banner or
+// header such as "Apache/2.4.58 (Ubuntu)".
+type Software struct {
+	Product string
+	Version string
+	// OS is the operating system hint some products append to their
+	// banner, e.g. "Ubuntu" or "Win64".
+	OS string
+}
+
+func (sw Software) String() string {
+	s := sw.Product
+	if sw.Version != "" {
+		s += "/" + sw.Version
+	}
+	if sw.OS != "" {
+		s += " (" + sw.OS + ")"
+	}
+	return s
+}
+
+func (sw Software) Known() bool {
+	return sw.Product != ""
+}
+
+// ParseServerHeader parses an HTTP Server header. Only the first product
+// token and the first comment are kept, so "Apache/2.4.58 (Ubuntu)
+// OpenSSL/3.0.2" yields Apache, 2.4.58 and Ubuntu.
+func ParseServerHeader(value string) Software {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Software{}
+	}
+
+	var sw Software
+	token, rest, _ := strings.Cut(value, " ")
+	sw.Product, sw.Version, _ = strings.Cut(token, "/")
+
+	if open := strings.Index(rest, "("); open >= 0 {
+		if end := strings.Index(rest[open:], ")"); end > 0 {
+			sw.OS = strings.TrimSpace(rest[open+1 : open+end])
+		}
+	}
+	return sw
+}
+
+// HeaderValue returns the value of header name in a raw HTTP response as
+// returned by Session.HTTP.
+func HeaderValue(resp, name string) string {
+	name = textproto.CanonicalMIMEHeaderKey(name)
+	for _, line := range strings.Split(resp, "\r\n")[1:] {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(key)) == name {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
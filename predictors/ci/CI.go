@@ -0,0 +1,125 @@
+package ci
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// Signature tells a CI/CD console apart from any other web application.
+// The response to Path, or to HEAD / when Path is empty, matches when it
+// carries Header and that header, or the whole response when Header is
+// empty, contains Contains. Only GET requests on Path read the body. The
+// version is the value of VersionHeader, or the first group of
+// VersionPattern in the response.
+type Signature struct {
+	Product        string
+	Path           string
+	Header         string
+	Contains       string
+	VersionHeader  string
+	VersionPattern *regexp.Regexp
+}
+
+// CI_SIGNATURES are the consoles CIPredictor knows by default. Append to it
+// before scanning to recognize more of them.
+var CI_SIGNATURES = []Signature{
+	{Product: "Jenkins", Header: "X-Jenkins", VersionHeader: "X-Jenkins"},
+	{Product: "Hudson", Header: "X-Hudson", VersionHeader: "X-Hudson"},
+	{Product: "TeamCity", Header: "TeamCity-Node-Id"},
+	{Product: "GitLab", Contains: "_gitlab_session="},
+	{Product: "GitLab", Path: "/users/sign_in", Contains: `content="GitLab"`},
+	{Product: "Gitea", Contains: "i_like_gitea="},
+	{Product: "Concourse", Path: "/api/v1/info", Contains: `"worker_version"`, VersionPattern: regexp.MustCompile(`"version":"([^"]+)"`)},
+}
+
+// CIPredictor recognizes the web consoles of CI/CD servers, such as
+// Jenkins or GitLab, by their signatures. A build server holds source
+// code, deploy credentials and often a script console, which makes it a
+// far more interesting finding than the web server in front of it.
+type CIPredictor struct {
+	predictors.BasePredictor
+	// Signatures replaces CI_SIGNATURES when set.
+	Signatures []Signature
+}
+
+func (p *CIPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *CIPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+func (p *CIPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	signatures := p.Signatures
+	if signatures == nil {
+		signatures = CI_SIGNATURES
+	}
+
+	responses := map[string]string{}
+	for _, sig := range signatures {
+		resp, ok := responses[sig.Path]
+		if !ok {
+			var err error
+			if resp, err = fetch(s, sig.Path); err != nil && sig.Path == "" {
+				// Not a web server: no other path will answer either.
+				return predictors.Match{}
+			}
+			responses[sig.Path] = resp
+		}
+		if !sig.matches(resp) {
+			continue
+		}
+
+		match := predictors.Certain(sig.Product)
+		match.Software = predictors.Software{Product: sig.Product, Version: sig.version(resp)}
+		if match.Software.Version != "" {
+			match.Description += " " + match.Software.Version
+		}
+		return match
+	}
+	return predictors.Match{}
+}
+
+func (sig Signature) matches(resp string) bool {
+	if !strings.HasPrefix(resp, "HTTP/") {
+		return false
+	}
+	text := resp
+	if sig.Header != "" {
+		if text = predictors.HeaderValue(resp, sig.Header); text == "" {
+			return false
+		}
+	}
+	return strings.Contains(text, sig.Contains)
+}
+
+func (sig Signature) version(resp string) string {
+	if sig.VersionHeader != "" {
+		return predictors.HeaderValue(resp, sig.VersionHeader)
+	}
+	if sig.VersionPattern != nil {
+		if m := sig.VersionPattern.FindStringSubmatch(resp); len(m) > 1 {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// fetch returns the headers of HEAD /, from the session cache, or the
+// headers and body of GET path.
+func fetch(s *predictors.Session, path string) (string, error) {
+	if path == "" {
+		return s.HTTP("HEAD", "/")
+	}
+	resp, err := s.Get(path)
+	if err != nil {
+		return "", err
+	}
+	return resp.Header + resp.Body, nil
+}
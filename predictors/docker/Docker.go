@@ -0,0 +1,74 @@
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// DockerPredictor reads GET /version from the Docker Engine API, in plain
+// HTTP on 2375 and over TLS on 2376. A daemon that answers without a
+// client certificate can be driven by anyone reaching the port, which is
+// as good as root on the host.
+type DockerPredictor struct {
+	predictors.BasePredictor
+}
+
+type version struct {
+	Version    string
+	ApiVersion string
+	Os         string
+}
+
+func (p *DockerPredictor) Ports() []int {
+	return []int{2375, 2376}
+}
+
+func (p *DockerPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *DockerPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+func (p *DockerPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	useTLS := s.Port() == 2376
+	get := s.Get
+	if useTLS {
+		get = s.GetTLS
+	}
+	resp, err := get("/version")
+	if err != nil {
+		// With TLS 1.3 a missing client certificate only shows up once
+		// the server speaks.
+		if useTLS && strings.Contains(err.Error(), "certificate") {
+			return predictors.Certain("Docker API (TLS: yes, client certificate required)")
+		}
+		return predictors.Match{}
+	}
+
+	var v version
+	if resp.StatusCode != http.StatusOK || json.Unmarshal([]byte(resp.Body), &v) != nil || v.ApiVersion == "" {
+		return predictors.Match{}
+	}
+
+	tlsNote := "TLS: no"
+	if useTLS {
+		tlsNote = "TLS: yes"
+	}
+	match := predictors.Certain(fmt.Sprintf("Docker API (v%s, API %s, %s)", v.Version, v.ApiVersion, tlsNote))
+	match.Software = predictors.Software{Product: "Docker", Version: v.Version, OS: v.Os}
+	// A client certificate of our own would have been the credential.
+	match.Unauthenticated = !useTLS || s.TLSConfig == nil || len(s.TLSConfig.Certificates) == 0
+	if !useTLS {
+		match.Description += " [ UNAUTHENTICATED: remote root on the host ]"
+	}
+	return match
+}
@@ -0,0 +1,103 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// GitPredictor detects git:// daemons by requesting git-upload-pack for
+// the root repository and reading the reply. The protocol has no way of
+// listing exported repositories, so only the root path is checked.
+type GitPredictor struct {
+	predictors.BasePredictor
+}
+
+func (p *GitPredictor) Ports() []int {
+	return []int{9418}
+}
+
+func (p *GitPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *GitPredictor) PredictSession(s *predictors.Session) string {
+	conn, err := s.Dial()
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	hostname, _, _ := net.SplitHostPort(s.Host)
+	if _, err := conn.Write(pktLine("git-upload-pack /\x00host=" + hostname + "\x00")); err != nil {
+		return ""
+	}
+
+	line, err := readPktLine(bufio.NewReader(s.Limit(conn)))
+	if err != nil {
+		return ""
+	}
+	if line == "" {
+		// A flush right away is the advertisement of an empty repository.
+		return "Git daemon (repository / exported, empty)"
+	}
+	if msg, isErr := strings.CutPrefix(line, "ERR "); isErr {
+		return "Git daemon (" + strings.TrimSpace(msg) + ")"
+	}
+
+	// The first ref line is "<sha> <ref>\x00<capabilities>".
+	ref, caps, ok := strings.Cut(line, "\x00")
+	if !ok || len(ref) < 41 || !isHex(ref[:40]) {
+		return ""
+	}
+	notes := []string{"repository / exported"}
+	for _, c := range strings.Fields(caps) {
+		if agent, found := strings.CutPrefix(c, "agent="); found {
+			notes = append(notes, agent)
+		}
+	}
+	return "Git daemon (" + strings.Join(notes, ", ") + ")"
+}
+
+func pktLine(payload string) []byte {
+	return []byte(fmt.Sprintf("%04x%s", len(payload)+4, payload))
+}
+
+func readPktLine(r *bufio.Reader) (string, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", err
+	}
+	length, err := strconv.ParseUint(string(header), 16, 16)
+	if err != nil {
+		return "", fmt.Errorf("git: bad pkt-line length %q", header)
+	}
+	if length == 0 {
+		return "", nil
+	}
+	if length < 4 {
+		return "", fmt.Errorf("git: bad pkt-line length %d", length)
+	}
+	payload := make([]byte, length-4)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(payload), "\n"), nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,245 @@
+package grpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+const (
+	reflectionPath = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+	streamID       = 1
+
+	// Protobuf field numbers of the reflection messages.
+	fieldListServices         = 7 // ServerReflectionRequest.list_services
+	fieldListServicesResponse = 6 // ServerReflectionResponse.list_services_response
+	fieldService              = 1 // ListServiceResponse.service
+	fieldServiceName          = 1 // ServiceResponse.name
+
+	grpcStatusUnimplemented = "12"
+)
+
+// GRPCPredictor speaks cleartext HTTP/2 (prior knowledge, as gRPC
+// servers without TLS expect) and asks the server reflection service for
+// the list of services. Servers with reflection disabled are still
+// recognised from their application/grpc responses.
+type GRPCPredictor struct {
+	predictors.BasePredictor
+}
+
+func (p *GRPCPredictor) Ports() []int {
+	return []int{50051}
+}
+
+func (p *GRPCPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *GRPCPredictor) PredictSession(s *predictors.Session) string {
+	conn, err := s.Dial()
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	if err := sendRequest(conn, s.Host); err != nil {
+		return ""
+	}
+	res, err := readResponse(conn, s.Limit(conn))
+	if !res.grpc {
+		return ""
+	}
+	if err != nil || res.status == grpcStatusUnimplemented || res.services == nil {
+		return "gRPC (reflection disabled)"
+	}
+	return "gRPC (services: " + strings.Join(res.services, ", ") + ")"
+}
+
+func sendRequest(conn net.Conn, host string) error {
+	if _, err := io.WriteString(conn, http2.ClientPreface); err != nil {
+		return err
+	}
+	framer := http2.NewFramer(conn, nil)
+	if err := framer.WriteSettings(); err != nil {
+		return err
+	}
+
+	var headers bytes.Buffer
+	enc := hpack.NewEncoder(&headers)
+	for _, f := range []hpack.HeaderField{
+		{Name: ":method", Value: "POST"},
+		{Name: ":scheme", Value: "http"},
+		{Name: ":path", Value: reflectionPath},
+		{Name: ":authority", Value: host},
+		{Name: "content-type", Value: "application/grpc"},
+		{Name: "te", Value: "trailers"},
+	} {
+		enc.WriteField(f)
+	}
+	if err := framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      streamID,
+		BlockFragment: headers.Bytes(),
+		EndHeaders:    true,
+	}); err != nil {
+		return err
+	}
+
+	// ServerReflectionRequest{list_services: ""} in a gRPC length-prefixed
+	// message.
+	msg := []byte{fieldListServices<<3 | 2, 0}
+	data := append([]byte{0}, binary.BigEndian.AppendUint32(nil, uint32(len(msg)))...)
+	return framer.WriteData(streamID, true, append(data, msg...))
+}
+
+type response struct {
+	grpc     bool
+	status   string
+	services []string
+}
+
+func readResponse(conn net.Conn, r io.Reader) (response, error) {
+	var res response
+	framer := http2.NewFramer(conn, r)
+	framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+
+	var body []byte
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			return res, err
+		}
+		switch f := frame.(type) {
+		case *http2.SettingsFrame:
+			if !f.IsAck() {
+				framer.WriteSettingsAck()
+			}
+		case *http2.PingFrame:
+			if !f.IsAck() {
+				framer.WritePing(true, f.Data)
+			}
+		case *http2.GoAwayFrame:
+			return res, errors.New("grpc: connection closed by server")
+		case *http2.RSTStreamFrame:
+			return res, errors.New("grpc: stream reset")
+		case *http2.MetaHeadersFrame:
+			if f.StreamID != streamID {
+				continue
+			}
+			if strings.HasPrefix(f.PseudoValue("status"), "2") || f.PseudoValue("status") == "" {
+				for _, field := range f.RegularFields() {
+					switch field.Name {
+					case "content-type":
+						res.grpc = res.grpc || strings.HasPrefix(field.Value, "application/grpc")
+					case "grpc-status":
+						res.grpc = true
+						res.status = field.Value
+					}
+				}
+			}
+			if f.StreamEnded() {
+				return res, nil
+			}
+		case *http2.DataFrame:
+			if f.StreamID != streamID {
+				continue
+			}
+			body = append(body, f.Data()...)
+			if services, ok := parseMessage(body); ok {
+				res.services = services
+				return res, nil
+			}
+			if f.StreamEnded() {
+				return res, nil
+			}
+		}
+	}
+}
+
+// parseMessage extracts the service names from the first gRPC message of
+// body, once it has been received in full.
+func parseMessage(body []byte) ([]string, bool) {
+	if len(body) < 5 || body[0] != 0 {
+		return nil, false
+	}
+	size := binary.BigEndian.Uint32(body[1:5])
+	if uint32(len(body)-5) < size {
+		return nil, false
+	}
+
+	list, ok := field(body[5:5+size], fieldListServicesResponse)
+	if !ok {
+		return nil, false
+	}
+	services := []string{}
+	for _, svc := range fields(list, fieldService) {
+		name, ok := field(svc, fieldServiceName)
+		if ok && !strings.HasPrefix(string(name), "grpc.reflection.") {
+			services = append(services, string(name))
+		}
+	}
+	sort.Strings(services)
+	return services, true
+}
+
+func field(msg []byte, number int) ([]byte, bool) {
+	all := fields(msg, number)
+	if len(all) == 0 {
+		return nil, false
+	}
+	return all[0], true
+}
+
+// fields returns every length-delimited field of msg with the given
+// number. Parsing stops at the first malformed field.
+func fields(msg []byte, number int) [][]byte {
+	var found [][]byte
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return found
+		}
+		msg = msg[n:]
+
+		switch key & 7 {
+		case 0: // varint
+			if _, n = binary.Uvarint(msg); n <= 0 {
+				return found
+			}
+			msg = msg[n:]
+		case 1: // 64-bit
+			if len(msg) < 8 {
+				return found
+			}
+			msg = msg[8:]
+		case 5: // 32-bit
+			if len(msg) < 4 {
+				return found
+			}
+			msg = msg[4:]
+		case 2: // length-delimited
+			size, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < size {
+				return found
+			}
+			if int(key>>3) == number {
+				found = append(found, msg[n:n+int(size)])
+			}
+			msg = msg[n+int(size):]
+		default:
+			return found
+		}
+	}
+	return found
+}
@@ -0,0 +1,62 @@
+package influxdb
+
+import (
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// InfluxDBPredictor reads the X-Influxdb-Version header InfluxDB sets on
+// every response, starting with the unauthenticated GET /ping. /health is
+// tried next for instances that protect /ping.
+type InfluxDBPredictor struct {
+	predictors.BasePredictor
+}
+
+func (p *InfluxDBPredictor) Ports() []int {
+	return []int{8086}
+}
+
+func (p *InfluxDBPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *InfluxDBPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+func (p *InfluxDBPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	for _, path := range []string{"/ping", "/health"} {
+		resp, err := s.HTTP("GET", path)
+		if err != nil {
+			return predictors.Match{}
+		}
+		status := strings.Fields(resp)
+		denied := len(status) > 1 && (status[1] == "401" || status[1] == "403")
+
+		version := predictors.HeaderValue(resp, "X-Influxdb-Version")
+		if version == "" && predictors.HeaderValue(resp, "X-Influxdb-Build") == "" {
+			if denied {
+				continue
+			}
+			return predictors.Match{}
+		}
+
+		description := "InfluxDB"
+		if version != "" {
+			description += " " + strings.TrimPrefix(version, "v")
+		}
+		if denied {
+			description += " (auth required)"
+		}
+		match := predictors.Certain(description)
+		if version != "" {
+			match.Software = predictors.Software{Product: "InfluxDB", Version: strings.TrimPrefix(version, "v")}
+		}
+		return match
+	}
+	return predictors.Match{}
+}
@@ -0,0 +1,109 @@
+package irc
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+const nick = "pscan"
+
+// IRCPredictor registers with the server and reads the ircd version from
+// the RPL_YOURHOST (002) or RPL_MYINFO (004) numerics.
+type IRCPredictor struct {
+	predictors.BasePredictor
+}
+
+func (p *IRCPredictor) Ports() []int {
+	return []int{194, 6667, 6697}
+}
+
+func (p *IRCPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *IRCPredictor) PredictSession(s *predictors.Session) string {
+	var conn net.Conn
+	var err error
+	if s.Port() == 6697 {
+		conn, err = s.DialTLS()
+	} else {
+		conn, err = s.Dial()
+	}
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	// Most servers wait for the registration sequence before sending
+	// anything but NOTICE AUTH, so send it right away.
+	if _, err := conn.Write([]byte("NICK " + nick + "\r\nUSER " + nick + " 0 * :" + nick + "\r\n")); err != nil {
+		return ""
+	}
+
+	isIRC, version := false, ""
+	reader := bufio.NewReader(s.Limit(conn))
+	for version == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		prefix, command, params := parseLine(strings.TrimRight(line, "\r\n"))
+		switch command {
+		case "PING":
+			conn.Write([]byte("PONG :" + strings.Join(params, " ") + "\r\n"))
+			isIRC = true
+		case "NOTICE", "001", "003", "005", "433", "451":
+			isIRC = true
+		case "002":
+			isIRC = true
+			if len(params) > 0 {
+				if _, v, ok := strings.Cut(params[len(params)-1], "running version "); ok {
+					version = strings.TrimSpace(v)
+				}
+			}
+		case "004":
+			isIRC = true
+			if len(params) > 2 {
+				version = params[2]
+			}
+		case "ERROR":
+			isIRC = isIRC || prefix == ""
+		}
+		if command == "ERROR" || command == "004" {
+			break
+		}
+	}
+	conn.Write([]byte("QUIT\r\n"))
+
+	if !isIRC {
+		return ""
+	}
+	if version == "" {
+		return "IRC"
+	}
+	return "IRC (" + version + ")"
+}
+
+// parseLine splits a raw IRC message into prefix, command and parameters.
+func parseLine(line string) (string, string, []string) {
+	var prefix string
+	if strings.HasPrefix(line, ":") {
+		prefix, line, _ = strings.Cut(line[1:], " ")
+	}
+	line, trailing, hasTrailing := strings.Cut(line, " :")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return prefix, "", nil
+	}
+	params := fields[1:]
+	if hasTrailing {
+		params = append(params, trailing)
+	}
+	return prefix, strings.ToUpper(fields[0]), params
+}
@@ -0,0 +1,119 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// KafkaPredictor sends an ApiVersions request, which brokers answer
+// before any authentication, and reports how many APIs the broker
+// supports along with the release range its ApiVersions version implies.
+// Brokers that only accept TLS are tried again over TLS.
+type KafkaPredictor struct {
+	predictors.BasePredictor
+}
+
+const (
+	apiVersionsKey = 18
+	correlationID  = 0x5053
+	clientID       = "port-scanner"
+
+	// errUnsupportedVersion still comes with the list of supported
+	// versions, so that clients can pick one.
+	errUnsupportedVersion = 35
+)
+
+// releases maps the highest ApiVersions version a broker supports to the
+// first release supporting it.
+var releases = []string{"0.10.0", "0.11.0", "2.0", "2.4", "3.7"}
+
+func (p *KafkaPredictor) Ports() []int {
+	return []int{9092}
+}
+
+func (p *KafkaPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *KafkaPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+func (p *KafkaPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	if match := apiVersions(s, false); match.Found() {
+		return match
+	}
+	return apiVersions(s, true)
+}
+
+func apiVersions(s *predictors.Session, useTLS bool) predictors.Match {
+	var conn net.Conn
+	var err error
+	if useTLS {
+		conn, err = s.DialTLS()
+	} else {
+		conn, err = s.Dial()
+	}
+	if err != nil {
+		return predictors.Match{}
+	}
+	defer conn.Close()
+
+	// ApiVersions v0 has an empty body: only the request header is sent.
+	req := make([]byte, 14, 14+len(clientID))
+	binary.BigEndian.PutUint32(req[0:], uint32(10+len(clientID)))
+	binary.BigEndian.PutUint16(req[4:], apiVersionsKey)
+	binary.BigEndian.PutUint16(req[6:], 0)
+	binary.BigEndian.PutUint32(req[8:], correlationID)
+	binary.BigEndian.PutUint16(req[12:], uint16(len(clientID)))
+	req = append(req, clientID...)
+	if _, err := conn.Write(req); err != nil {
+		return predictors.Match{}
+	}
+
+	r := s.Limit(conn)
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil || size < 10 || size > 1<<16 {
+		return predictors.Match{}
+	}
+	resp := make([]byte, size)
+	if _, err := io.ReadFull(r, resp); err != nil {
+		return predictors.Match{}
+	}
+	if binary.BigEndian.Uint32(resp[0:]) != correlationID {
+		return predictors.Match{}
+	}
+	code := binary.BigEndian.Uint16(resp[4:])
+	if code != 0 && code != errUnsupportedVersion {
+		return predictors.Match{}
+	}
+
+	count := int(binary.BigEndian.Uint32(resp[6:]))
+	entries := resp[10:]
+	if count <= 0 || len(entries) < count*6 {
+		return predictors.Match{}
+	}
+	maxVersion := -1
+	for i := 0; i < count; i++ {
+		entry := entries[i*6:]
+		if binary.BigEndian.Uint16(entry[0:]) == apiVersionsKey {
+			maxVersion = int(binary.BigEndian.Uint16(entry[4:]))
+		}
+	}
+
+	description := fmt.Sprintf("Kafka (%d APIs", count)
+	if maxVersion >= 0 {
+		description += ", broker " + releases[min(maxVersion, len(releases)-1)] + "+"
+	}
+	if useTLS {
+		description += ", TLS"
+	}
+	return predictors.Certain(description + ")")
+}
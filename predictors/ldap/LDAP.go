@@ -0,0 +1,252 @@
+package ldap
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+
+	appBindRequest     = 0x60
+	appBindResponse    = 0x61
+	appSearchRequest   = 0x63
+	appSearchEntry     = 0x64
+	appSearchDone      = 0x65
+	ctxSimpleAuth      = 0x80
+	ctxPresentFilter   = 0x87
+	resultSuccess      = 0
+	maxLDAPMessageSize = 64 * 1024
+)
+
+type LDAPPredictor struct {
+	predictors.BasePredictor
+}
+
+func (p *LDAPPredictor) Ports() []int {
+	return []int{389, 636}
+}
+
+func (p *LDAPPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+// PredictSession performs an anonymous simple bind and, if the server
+// answers, reads the vendor attributes of the rootDSE.
+func (p *LDAPPredictor) PredictSession(s *predictors.Session) string {
+	var conn net.Conn
+	var err error
+	if s.Port() == 636 {
+		conn, err = s.DialTLS()
+	} else {
+		conn, err = s.Dial()
+	}
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	r := s.Limit(conn)
+	if _, err := conn.Write(bindRequest(1)); err != nil {
+		return ""
+	}
+	op, body, err := readMessage(r)
+	if err != nil || op != appBindResponse {
+		return ""
+	}
+	code, err := resultCode(body)
+	if err != nil {
+		return ""
+	}
+
+	var notes []string
+	if code == resultSuccess {
+		if vendor := readVendor(conn, r); vendor != "" {
+			notes = append(notes, vendor)
+		}
+		notes = append(notes, "anonymous bind allowed")
+	} else {
+		notes = append(notes, "anonymous bind refused")
+	}
+	return "LDAP (" + strings.Join(notes, ", ") + ")"
+}
+
+func readVendor(conn net.Conn, r io.Reader) string {
+	if _, err := conn.Write(rootDSESearch(2)); err != nil {
+		return ""
+	}
+
+	attrs := map[string]string{}
+	for {
+		op, body, err := readMessage(r)
+		if err != nil || op != appSearchEntry {
+			break
+		}
+		collectAttributes(body, attrs)
+	}
+	return strings.TrimSpace(attrs["vendorname"] + " " + attrs["vendorversion"])
+}
+
+func bindRequest(id int) []byte {
+	bind := tlv(appBindRequest,
+		tlv(tagInteger, []byte{3}),
+		tlv(tagOctetString),
+		tlv(ctxSimpleAuth),
+	)
+	return tlv(tagSequence, tlv(tagInteger, []byte{byte(id)}), bind)
+}
+
+func rootDSESearch(id int) []byte {
+	search := tlv(appSearchRequest,
+		tlv(tagOctetString),
+		tlv(tagEnumerated, []byte{0}),
+		tlv(tagEnumerated, []byte{0}),
+		tlv(tagInteger, []byte{0}),
+		tlv(tagInteger, []byte{0}),
+		tlv(0x01, []byte{0}),
+		tlv(ctxPresentFilter, []byte("objectClass")),
+		tlv(tagSequence,
+			tlv(tagOctetString, []byte("vendorName")),
+			tlv(tagOctetString, []byte("vendorVersion")),
+		),
+	)
+	return tlv(tagSequence, tlv(tagInteger, []byte{byte(id)}), search)
+}
+
+func tlv(tag byte, contents ...[]byte) []byte {
+	var body []byte
+	for _, c := range contents {
+		body = append(body, c...)
+	}
+
+	out := []byte{tag}
+	switch n := len(body); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xff:
+		out = append(out, 0x81, byte(n))
+	default:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	}
+	return append(out, body...)
+}
+
+// readMessage reads one LDAPMessage and returns its protocolOp tag and
+// contents.
+func readMessage(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+	if header[0] != tagSequence {
+		return 0, nil, errors.New("ldap: not an LDAPMessage")
+	}
+
+	length := int(header[1])
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 {
+			return 0, nil, errors.New("ldap: unsupported length")
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return 0, nil, err
+		}
+		length = 0
+		for _, b := range buf {
+			length = length<<8 | int(b)
+		}
+	}
+	if length > maxLDAPMessageSize {
+		return 0, nil, errors.New("ldap: message too large")
+	}
+
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return 0, nil, err
+	}
+
+	// messageID, then the protocolOp.
+	_, _, rest, err := parseTLV(msg)
+	if err != nil {
+		return 0, nil, err
+	}
+	tag, body, _, err := parseTLV(rest)
+	return tag, body, err
+}
+
+func parseTLV(b []byte) (tag byte, body, rest []byte, err error) {
+	if len(b) < 2 {
+		return 0, nil, nil, errors.New("ldap: short element")
+	}
+	tag = b[0]
+	length := int(b[1])
+	b = b[2:]
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 || len(b) < n {
+			return 0, nil, nil, errors.New("ldap: bad length")
+		}
+		length = 0
+		for _, c := range b[:n] {
+			length = length<<8 | int(c)
+		}
+		b = b[n:]
+	}
+	if length > len(b) {
+		return 0, nil, nil, errors.New("ldap: truncated element")
+	}
+	return tag, b[:length], b[length:], nil
+}
+
+func resultCode(body []byte) (int, error) {
+	tag, code, _, err := parseTLV(body)
+	if err != nil {
+		return 0, err
+	}
+	if tag != tagEnumerated || len(code) != 1 {
+		return 0, errors.New("ldap: bad resultCode")
+	}
+	return int(code[0]), nil
+}
+
+// collectAttributes stores the first value of every attribute of a
+// SearchResultEntry into attrs, keyed by lower-cased attribute name.
+func collectAttributes(entry []byte, attrs map[string]string) {
+	_, _, rest, err := parseTLV(entry) // objectName
+	if err != nil {
+		return
+	}
+	_, list, _, err := parseTLV(rest)
+	if err != nil {
+		return
+	}
+	for len(list) > 0 {
+		var attr []byte
+		if _, attr, list, err = parseTLV(list); err != nil {
+			return
+		}
+		_, name, vals, err := parseTLV(attr)
+		if err != nil {
+			return
+		}
+		tag, set, _, err := parseTLV(vals)
+		if err != nil || tag != tagSet {
+			continue
+		}
+		if _, val, _, err := parseTLV(set); err == nil {
+			attrs[strings.ToLower(string(name))] = string(val)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"bufio"
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// IMAPPredictor reads the "* OK" greeting and sends CAPABILITY. Every
+// atom of the untagged CAPABILITY responses is a capability, e.g.
+// "STARTTLS" or "AUTH=PLAIN".
+type IMAPPredictor struct {
+	predictors.BasePredictor
+}
+
+func (p *IMAPPredictor) Ports() []int {
+	return []int{143, 993}
+}
+
+func (p *IMAPPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *IMAPPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+func (p *IMAPPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	conn, err := dial(s, 993)
+	if err != nil {
+		return predictors.Match{}
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(s.Limit(conn))
+	restore := s.AwaitGreeting(conn)
+	greeting, err := readLine(r)
+	restore()
+	if err != nil {
+		return predictors.Match{}
+	}
+	text, ok := strings.CutPrefix(greeting, "* OK")
+	if !ok {
+		if strings.HasPrefix(greeting, "* PREAUTH") {
+			return predictors.Certain("IMAP (preauthenticated)")
+		}
+		return predictors.Match{}
+	}
+	// Many servers put their capabilities in a response code of the
+	// greeting; the text after it names the server.
+	if code, rest, found := strings.Cut(strings.TrimSpace(text), "]"); found && strings.HasPrefix(code, "[") {
+		text = rest
+	}
+	match := predictors.Certain(describe("IMAP", text))
+
+	if _, err := conn.Write([]byte("a1 CAPABILITY\r\n")); err != nil {
+		return match
+	}
+	var capabilities []string
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return match
+		}
+		if atoms, ok := strings.CutPrefix(line, "* CAPABILITY "); ok {
+			capabilities = append(capabilities, strings.Fields(atoms)...)
+			continue
+		}
+		if strings.HasPrefix(line, "a1 ") {
+			break
+		}
+	}
+	match.Capabilities = capabilities
+	conn.Write([]byte("a2 LOGOUT\r\n"))
+	return match
+}
@@ -0,0 +1,41 @@
+// Package mail holds the predictors of mail servers. Besides the greeting
+// they enumerate the capabilities the server advertises, such as STARTTLS
+// and the AUTH mechanisms, which is what a mail server audit looks at.
+package mail
+
+import (
+	"bufio"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// dial connects to the session's port, handshaking first on the ports
+// that speak TLS from the start.
+func dial(s *predictors.Session, tlsPorts ...int) (net.Conn, error) {
+	if slices.Contains(tlsPorts, s.Port()) {
+		return s.DialTLS()
+	}
+	return s.Dial()
+}
+
+// readLine reads one CRLF terminated line, without the terminator.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// describe names the protocol and the greeting text, which usually
+// carries the host name and the server software.
+func describe(protocol, greeting string) string {
+	greeting = strings.TrimSpace(greeting)
+	if greeting == "" {
+		return protocol
+	}
+	return protocol + " (" + greeting + ")"
+}
@@ -0,0 +1,76 @@
+package mail
+
+import (
+	"bufio"
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// POP3Predictor reads the "+OK" greeting and sends CAPA, whose multi-line
+// answer has one capability per line, e.g. "STLS" or "SASL PLAIN LOGIN".
+type POP3Predictor struct {
+	predictors.BasePredictor
+}
+
+func (p *POP3Predictor) Ports() []int {
+	return []int{110, 995}
+}
+
+func (p *POP3Predictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *POP3Predictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+func (p *POP3Predictor) PredictMatch(s *predictors.Session) predictors.Match {
+	conn, err := dial(s, 995)
+	if err != nil {
+		return predictors.Match{}
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(s.Limit(conn))
+	restore := s.AwaitGreeting(conn)
+	greeting, err := readLine(r)
+	restore()
+	if err != nil {
+		return predictors.Match{}
+	}
+	text, ok := strings.CutPrefix(greeting, "+OK")
+	if !ok {
+		return predictors.Match{}
+	}
+	// APOP servers end the greeting with a timestamp that is of no use
+	// to identify them.
+	if i := strings.LastIndex(text, " <"); i >= 0 && strings.HasSuffix(text, ">") {
+		text = text[:i]
+	}
+	match := predictors.Certain(describe("POP3", text))
+
+	if _, err := conn.Write([]byte("CAPA\r\n")); err != nil {
+		return match
+	}
+	if status, err := readLine(r); err != nil || !strings.HasPrefix(status, "+OK") {
+		return match
+	}
+	var capabilities []string
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return match
+		}
+		if line == "." {
+			break
+		}
+		capabilities = append(capabilities, line)
+	}
+	match.Capabilities = capabilities
+	conn.Write([]byte("QUIT\r\n"))
+	return match
+}
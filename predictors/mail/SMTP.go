@@ -0,0 +1,77 @@
+package mail
+
+import (
+	"bufio"
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// SMTPPredictor reads the 220 greeting and lists the extensions the server
+// answers EHLO with, one capability per line of the reply, e.g. "STARTTLS"
+// or "AUTH PLAIN LOGIN".
+type SMTPPredictor struct {
+	predictors.BasePredictor
+}
+
+func (p *SMTPPredictor) Ports() []int {
+	return []int{25, 465, 587}
+}
+
+func (p *SMTPPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *SMTPPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+func (p *SMTPPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	conn, err := dial(s, 465)
+	if err != nil {
+		return predictors.Match{}
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(s.Limit(conn))
+	restore := s.AwaitGreeting(conn)
+	greeting, ok := readReply(r, "220")
+	restore()
+	if !ok || len(greeting) == 0 {
+		return predictors.Match{}
+	}
+	match := predictors.Certain(describe("SMTP", greeting[0]))
+
+	if _, err := conn.Write([]byte("EHLO port-scanner\r\n")); err != nil {
+		return match
+	}
+	// The first line of the reply greets the client; the extensions
+	// follow.
+	if reply, ok := readReply(r, "250"); ok && len(reply) > 1 {
+		match.Capabilities = reply[1:]
+	}
+	conn.Write([]byte("QUIT\r\n"))
+	return match
+}
+
+// readReply reads a possibly multi-line reply, where every line but the
+// last has a dash after the code, and returns the text of its lines. It
+// fails unless the reply has the given code.
+func readReply(r *bufio.Reader, code string) ([]string, bool) {
+	var lines []string
+	for {
+		line, err := readLine(r)
+		if err != nil || len(line) < 3 || line[:3] != code {
+			return nil, false
+		}
+		text := strings.TrimSpace(line[3:])
+		last := !strings.HasPrefix(text, "-")
+		lines = append(lines, strings.TrimSpace(strings.TrimPrefix(text, "-")))
+		if last {
+			return lines, true
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package mongodb
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+const (
+	opMsg          = 2013
+	maxMessageSize = 64 * 1024
+
+	bsonDouble   = 0x01
+	bsonString   = 0x02
+	bsonDocument = 0x03
+	bsonArray    = 0x04
+	bsonBinary   = 0x05
+	bsonObjectID = 0x07
+	bsonBool     = 0x08
+	bsonDateTime = 0x09
+	bsonNull     = 0x0a
+	bsonInt32    = 0x10
+	bsonInt64    = 0x12
+	bsonTime     = 0x11
+	bsonDecimal  = 0x13
+)
+
+// MongoDBPredictor speaks OP_MSG, so only servers from 3.6 on are
+// recognised. hello, or isMaster on servers before 4.4.2 that lack it, and
+// buildInfo never require credentials; whether the instance is open is
+// decided by listDatabases, which does.
+type MongoDBPredictor struct {
+	predictors.BasePredictor
+}
+
+func (p *MongoDBPredictor) Ports() []int {
+	return []int{27017}
+}
+
+func (p *MongoDBPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *MongoDBPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+func (p *MongoDBPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	conn, err := s.Dial()
+	if err != nil {
+		return predictors.Match{}
+	}
+	defer conn.Close()
+
+	hello, err := command(s, conn, 1, "hello")
+	if err == nil && !ok(hello) {
+		// Servers before 4.4.2 answer "no such command".
+		hello, err = command(s, conn, 2, "isMaster")
+	}
+	if err != nil || !ok(hello) {
+		return predictors.Match{}
+	}
+
+	version := ""
+	if info, err := command(s, conn, 3, "buildInfo"); err == nil {
+		version, _ = info["version"].(string)
+	}
+	dbs, err := command(s, conn, 4, "listDatabases")
+	open := err == nil && ok(dbs)
+
+	description := "MongoDB"
+	if version != "" {
+		description += " " + version
+	}
+	if open {
+		description += " (unauthenticated)"
+	} else {
+		description += " (auth required)"
+	}
+
+	match := predictors.Certain(description)
+	match.Unauthenticated = open
+	if version != "" {
+		match.Software = predictors.Software{Product: "MongoDB", Version: version}
+	}
+	return match
+}
+
+func ok(doc map[string]any) bool {
+	switch v := doc["ok"].(type) {
+	case float64:
+		return v == 1
+	case int32:
+		return v == 1
+	case int64:
+		return v == 1
+	}
+	return false
+}
+
+// command runs {name: 1, $db: "admin"} and returns the top-level fields
+// of the reply. Each reply gets its own MaxResponseBytes budget, as a
+// buildInfo reply alone can take most of it.
+func command(s *predictors.Session, conn net.Conn, id int32, name string) (map[string]any, error) {
+	doc := document(
+		element(bsonInt32, name, int32Bytes(1)),
+		element(bsonString, "$db", stringBytes("admin")),
+	)
+
+	// flagBits, then a single body section.
+	body := append(make([]byte, 4), 0)
+	body = append(body, doc...)
+
+	header := make([]byte, 16)
+	binary.LittleEndian.PutUint32(header[0:], uint32(16+len(body)))
+	binary.LittleEndian.PutUint32(header[4:], uint32(id))
+	binary.LittleEndian.PutUint32(header[12:], opMsg)
+	if _, err := conn.Write(append(header, body...)); err != nil {
+		return nil, err
+	}
+	return readReply(s.Limit(conn))
+}
+
+func readReply(r io.Reader) (map[string]any, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := int(binary.LittleEndian.Uint32(header[0:]))
+	if binary.LittleEndian.Uint32(header[12:]) != opMsg {
+		return nil, errors.New("mongodb: not an OP_MSG reply")
+	}
+	if length < 16+5 || length > maxMessageSize {
+		return nil, errors.New("mongodb: bad message length")
+	}
+
+	msg := make([]byte, length-16)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	if msg[4] != 0 {
+		return nil, errors.New("mongodb: unexpected section kind")
+	}
+	return parseDocument(msg[5:])
+}
+
+func document(elements ...[]byte) []byte {
+	var body []byte
+	for _, e := range elements {
+		body = append(body, e...)
+	}
+	body = append(body, 0)
+	return append(int32Bytes(int32(4+len(body))), body...)
+}
+
+func element(kind byte, name string, value []byte) []byte {
+	out := append([]byte{kind}, name...)
+	out = append(out, 0)
+	return append(out, value...)
+}
+
+func int32Bytes(v int32) []byte {
+	return binary.LittleEndian.AppendUint32(nil, uint32(v))
+}
+
+func stringBytes(s string) []byte {
+	out := int32Bytes(int32(len(s) + 1))
+	out = append(out, s...)
+	return append(out, 0)
+}
+
+// parseDocument decodes the scalar top-level fields of a BSON document.
+// Nested documents and other types are skipped.
+func parseDocument(b []byte) (map[string]any, error) {
+	if len(b) < 5 {
+		return nil, errors.New("mongodb: short document")
+	}
+	size := int(binary.LittleEndian.Uint32(b))
+	if size < 5 || size > len(b) {
+		return nil, errors.New("mongodb: bad document length")
+	}
+	b = b[4 : size-1]
+
+	fields := map[string]any{}
+	for len(b) > 0 {
+		kind := b[0]
+		end := 1
+		for end < len(b) && b[end] != 0 {
+			end++
+		}
+		if end == len(b) {
+			return nil, errors.New("mongodb: unterminated field name")
+		}
+		name := string(b[1:end])
+		b = b[end+1:]
+
+		n, value, err := parseValue(kind, b)
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			fields[name] = value
+		}
+		b = b[n:]
+	}
+	return fields, nil
+}
+
+// parseValue returns how many bytes the value of the given kind takes and,
+// for scalars, the value itself.
+func parseValue(kind byte, b []byte) (int, any, error) {
+	fixed := map[byte]int{
+		bsonDouble: 8, bsonObjectID: 12, bsonBool: 1, bsonDateTime: 8,
+		bsonNull: 0, bsonInt32: 4, bsonTime: 8, bsonInt64: 8, bsonDecimal: 16,
+	}
+
+	var n int
+	switch kind {
+	case bsonString:
+		if len(b) < 4 {
+			return 0, nil, errors.New("mongodb: truncated string")
+		}
+		n = 4 + int(binary.LittleEndian.Uint32(b))
+		if n < 5 || n > len(b) {
+			return 0, nil, errors.New("mongodb: bad string length")
+		}
+		return n, string(b[4 : n-1]), nil
+	case bsonDocument, bsonArray:
+		if len(b) < 4 {
+			return 0, nil, errors.New("mongodb: truncated document")
+		}
+		n = int(binary.LittleEndian.Uint32(b))
+	case bsonBinary:
+		if len(b) < 4 {
+			return 0, nil, errors.New("mongodb: truncated binary")
+		}
+		n = 5 + int(binary.LittleEndian.Uint32(b))
+	default:
+		size, known := fixed[kind]
+		if !known {
+			return 0, nil, errors.New("mongodb: unsupported BSON type")
+		}
+		n = size
+	}
+	if n < 0 || n > len(b) {
+		return 0, nil, errors.New("mongodb: truncated value")
+	}
+
+	switch kind {
+	case bsonDouble:
+		return n, math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case bsonBool:
+		return n, b[0] == 1, nil
+	case bsonInt32:
+		return n, int32(binary.LittleEndian.Uint32(b)), nil
+	case bsonInt64:
+		return n, int64(binary.LittleEndian.Uint64(b)), nil
+	}
+	return n, nil, nil
+}
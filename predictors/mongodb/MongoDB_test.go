@@ -0,0 +1,99 @@
+package mongodb
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// serveMongo answers OP_MSG commands with the reply replies has for them,
+// or a "no such command" error.
+func serveMongo(t *testing.T, replies map[string][]byte) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for {
+					name, id, err := readCommand(conn)
+					if err != nil {
+						return
+					}
+					reply, known := replies[name]
+					if !known {
+						reply = document(
+							element(bsonDouble, "ok", doubleBytes(0)),
+							element(bsonString, "errmsg", stringBytes("no such command: '"+name+"'")),
+						)
+					}
+					body := append(make([]byte, 5), reply...)
+					header := make([]byte, 16)
+					binary.LittleEndian.PutUint32(header[0:], uint32(16+len(body)))
+					binary.LittleEndian.PutUint32(header[8:], id)
+					binary.LittleEndian.PutUint32(header[12:], opMsg)
+					conn.Write(append(header, body...))
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// readCommand reads an OP_MSG request and returns the command it runs.
+func readCommand(r io.Reader) (string, uint32, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", 0, err
+	}
+	body := make([]byte, binary.LittleEndian.Uint32(header)-16)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return "", 0, err
+	}
+	// The command is the first field of the body section.
+	doc := body[5:]
+	end := 5
+	for doc[end] != 0 {
+		end++
+	}
+	return string(doc[5:end]), binary.LittleEndian.Uint32(header[4:]), nil
+}
+
+func doubleBytes(v float64) []byte {
+	return binary.LittleEndian.AppendUint64(nil, math.Float64bits(v))
+}
+
+func TestPredictMatchFallsBackToIsMaster(t *testing.T) {
+	okDoc := document(element(bsonDouble, "ok", doubleBytes(1)))
+	addr := serveMongo(t, map[string][]byte{
+		"isMaster": document(
+			element(bsonBool, "ismaster", []byte{1}),
+			element(bsonDouble, "ok", doubleBytes(1)),
+		),
+		"buildInfo": document(
+			element(bsonString, "version", stringBytes("4.2.8")),
+			element(bsonDouble, "ok", doubleBytes(1)),
+		),
+		"listDatabases": okDoc,
+	})
+
+	s := predictors.NewSession(addr, time.Second)
+	defer s.Close()
+	match := (&MongoDBPredictor{}).PredictMatch(s)
+	if match.Description != "MongoDB 4.2.8 (unauthenticated)" || !match.Unauthenticated {
+		t.Fatalf("PredictMatch = %+v, want an unauthenticated MongoDB 4.2.8", match)
+	}
+}
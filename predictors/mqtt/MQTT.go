@@ -0,0 +1,99 @@
+package mqtt
+
+import (
+	"io"
+	"net"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// MQTTPredictor sends an MQTT 3.1.1 CONNECT without credentials, in plain
+// on 1883 and over TLS on 8883, and reads the return code of the CONNACK.
+// A broker accepting it lets anyone subscribe to every topic, which on
+// IoT deployments often means sensor data and device commands.
+type MQTTPredictor struct {
+	predictors.BasePredictor
+}
+
+const (
+	packetConnect    = 0x10
+	packetConnack    = 0x20
+	packetDisconnect = 0xe0
+
+	clientID = "port-scanner"
+)
+
+// refusals describes the CONNACK return codes refusing the connection.
+var refusals = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "authentication required",
+	5: "authentication required",
+}
+
+func (p *MQTTPredictor) Ports() []int {
+	return []int{1883, 8883}
+}
+
+func (p *MQTTPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *MQTTPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+func (p *MQTTPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	useTLS := s.Port() == 8883
+	var conn net.Conn
+	var err error
+	if useTLS {
+		conn, err = s.DialTLS()
+	} else {
+		conn, err = s.Dial()
+	}
+	if err != nil {
+		return predictors.Match{}
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write(connectPacket()); err != nil {
+		return predictors.Match{}
+	}
+	connack := make([]byte, 4)
+	if _, err := io.ReadFull(s.Limit(conn), connack); err != nil {
+		return predictors.Match{}
+	}
+	if connack[0] != packetConnack || connack[1] != 2 {
+		return predictors.Match{}
+	}
+
+	tls := ""
+	if useTLS {
+		tls = ", TLS"
+	}
+	code := connack[3]
+	if code == 0 {
+		conn.Write([]byte{packetDisconnect, 0})
+		match := predictors.Certain("MQTT (anonymous allowed" + tls + ")")
+		match.Unauthenticated = true
+		return match
+	}
+	reason, ok := refusals[code]
+	if !ok {
+		return predictors.Match{}
+	}
+	return predictors.Certain("MQTT (" + reason + tls + ")")
+}
+
+// connectPacket is a CONNECT with a clean session, no credentials and a
+// minute of keep-alive.
+func connectPacket() []byte {
+	body := []byte{0, 4, 'M', 'Q', 'T', 'T', 4, 0x02, 0, 60, 0, byte(len(clientID))}
+	body = append(body, clientID...)
+	return append([]byte{packetConnect, byte(len(body))}, body...)
+}
@@ -0,0 +1,186 @@
+package ntp
+
+import (
+	"encoding/binary"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// NTPPredictor sends a mode 6 (control) READVAR request over UDP, which
+// ntpd answers with its system variables unless restricted, and reads the
+// version, system and processor from them. It then asks for the mode 7
+// monlist, which a server answering can be abused to amplify traffic
+// towards a spoofed address.
+type NTPPredictor struct {
+	predictors.BasePredictor
+}
+
+func (p *NTPPredictor) UDPPorts() []int {
+	return []int{123}
+}
+
+func (p *NTPPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	s.Network = "udp"
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *NTPPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+const (
+	modeControl = 6
+	modePrivate = 7
+
+	opReadVariables = 2
+	reqMonGetList   = 42
+
+	// An ntpd response is split in fragments; a few are plenty for the
+	// system variables.
+	maxFragments = 8
+)
+
+func (p *NTPPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	vars, ok := readVariables(s)
+	if !ok {
+		return predictors.Match{}
+	}
+
+	product, version := parseVersion(vars["version"])
+	system, _, _ := strings.Cut(vars["system"], "/")
+	var details []string
+	if product != "" {
+		details = append(details, strings.TrimSpace(product+" "+version))
+	}
+	if system != "" {
+		details = append(details, system)
+	}
+	if processor := vars["processor"]; processor != "" {
+		details = append(details, processor)
+	}
+
+	match := predictors.Certain("NTP")
+	if len(details) > 0 {
+		match.Description += " (" + strings.Join(details, ", ") + ")"
+	}
+	match.Software = predictors.Software{Product: product, Version: version, OS: system}
+	if monlist(s) {
+		match.Description += " [ monlist enabled: usable for DDoS amplification ]"
+	}
+	return match
+}
+
+// readVariables sends READVAR for the system association and returns the
+// variables of the reassembled answer.
+func readVariables(s *predictors.Session) (map[string]string, bool) {
+	conn, err := s.Dial()
+	if err != nil {
+		return nil, false
+	}
+	defer conn.Close()
+
+	req := make([]byte, 12)
+	req[0] = 2<<3 | modeControl
+	req[1] = opReadVariables
+	binary.BigEndian.PutUint16(req[2:], 1)
+	if _, err := conn.Write(req); err != nil {
+		return nil, false
+	}
+
+	type fragment struct {
+		offset int
+		data   []byte
+	}
+	var fragments []fragment
+	buf := make([]byte, 2048)
+	for len(fragments) < maxFragments {
+		n, err := conn.Read(buf)
+		if err != nil {
+			break
+		}
+		resp := buf[:n]
+		// Responses have the R bit set and echo the opcode and sequence.
+		if n < 12 || resp[0]&0x07 != modeControl || resp[1]&0x80 == 0 || resp[1]&0x1f != opReadVariables ||
+			binary.BigEndian.Uint16(resp[2:]) != 1 {
+			continue
+		}
+		if resp[1]&0x40 != 0 {
+			// The server refused the request.
+			return nil, false
+		}
+		offset := int(binary.BigEndian.Uint16(resp[8:]))
+		count := min(int(binary.BigEndian.Uint16(resp[10:])), n-12)
+		fragments = append(fragments, fragment{offset, append([]byte(nil), resp[12:12+count]...)})
+		if resp[1]&0x20 == 0 {
+			break
+		}
+	}
+	if len(fragments) == 0 {
+		return nil, false
+	}
+
+	sort.Slice(fragments, func(i, j int) bool { return fragments[i].offset < fragments[j].offset })
+	var data strings.Builder
+	for _, f := range fragments {
+		data.Write(f.data)
+	}
+	return parseVariables(data.String()), true
+}
+
+// parseVariables splits the comma separated name=value list of a mode 6
+// answer. Values may be quoted and contain commas.
+func parseVariables(data string) map[string]string {
+	vars := map[string]string{}
+	for len(data) > 0 {
+		data = strings.TrimLeft(data, ", \r\n\x00")
+		name, rest, found := strings.Cut(data, "=")
+		if !found {
+			break
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		vars[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		data = rest
+	}
+	return vars
+}
+
+// parseVersion reads a version variable such as
+// "ntpd 4.2.8p15@1.3728-o Wed Sep 23 11:46:38 UTC 2020 (1)".
+func parseVersion(v string) (product, version string) {
+	v, _, _ = strings.Cut(v, "@")
+	product, version, _ = strings.Cut(strings.TrimSpace(v), " ")
+	return product, strings.TrimSpace(version)
+}
+
+// monlist tells whether the server answers a mode 7 MON_GETLIST_1 request
+// without an error.
+func monlist(s *predictors.Session) bool {
+	conn, err := s.Dial()
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	// Implementation 3 is XNTPD.
+	req := []byte{2<<3 | modePrivate, 0, 3, reqMonGetList, 0, 0, 0, 0}
+	if _, err := conn.Write(req); err != nil {
+		return false
+	}
+	buf := make([]byte, 512)
+	n, err := conn.Read(buf)
+	if err != nil || n < 8 {
+		return false
+	}
+	resp := buf[:n]
+	return resp[0]&0x80 != 0 && resp[0]&0x07 == modePrivate && resp[3] == reqMonGetList && resp[4]>>4 == 0
+}
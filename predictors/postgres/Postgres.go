@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// PostgresPredictor sends an SSLRequest and reports whether the server
+// offers TLS. When it does, a cleartext startup tells whether it insists
+// on it, and whether it lets the postgres user in without a password.
+// The release range comes from the protocol versions the server admits
+// to when asked for one it does not support.
+type PostgresPredictor struct {
+	predictors.BasePredictor
+}
+
+const (
+	sslRequestCode = 80877103
+	protocol30     = 3 << 16
+	// protocol40 does not exist: servers reject it with the range of
+	// versions they support.
+	protocol40 = 4 << 16
+
+	authOK = 0
+	// maxMessage bounds the messages read, well above any startup reply.
+	maxMessage = 1 << 16
+)
+
+var supportsPattern = regexp.MustCompile(`server supports (\d+\.\d+) to (\d+\.\d+)`)
+
+// releases maps the protocol range a server supports to the releases
+// supporting it: 14 dropped protocol 2.0 and 18 added 3.2.
+var releases = map[string]string{
+	"2.0 to 3.0": "before 14",
+	"3.0 to 3.0": "14 to 17",
+	"3.0 to 3.2": "18 or later",
+}
+
+func (p *PostgresPredictor) Ports() []int {
+	return []int{5432, 6432}
+}
+
+func (p *PostgresPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *PostgresPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+func (p *PostgresPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	offered, ok := sslRequest(s)
+	if !ok {
+		return predictors.Match{}
+	}
+
+	match := predictors.Certain("PostgreSQL")
+	ssl := "disabled"
+	if offered {
+		ssl = "available"
+	}
+	switch msgType, body := startup(s, protocol30); {
+	case msgType == 'R' && len(body) >= 4 && binary.BigEndian.Uint32(body) == authOK:
+		match.Unauthenticated = true
+	case msgType == 'E' && offered && requiresSSL(errorMessage(body)):
+		ssl = "required"
+	}
+
+	details := []string{"SSL: " + ssl}
+	if match.Unauthenticated {
+		details = append(details, "trust authentication")
+	}
+	if _, body := startup(s, protocol40); body != nil {
+		if m := supportsPattern.FindStringSubmatch(errorMessage(body)); m != nil {
+			if release, ok := releases[m[1]+" to "+m[2]]; ok {
+				details = append(details, "version "+release)
+			}
+		}
+	}
+	match.Description += " (" + strings.Join(details, ", ") + ")"
+	match.Software = predictors.Software{Product: "PostgreSQL"}
+	return match
+}
+
+// sslRequest asks the server for TLS. ok is false when the answer is not
+// the single byte PostgreSQL answers with.
+func sslRequest(s *predictors.Session) (offered, ok bool) {
+	conn, err := s.Dial()
+	if err != nil {
+		return false, false
+	}
+	defer conn.Close()
+
+	req := binary.BigEndian.AppendUint32(nil, 8)
+	req = binary.BigEndian.AppendUint32(req, sslRequestCode)
+	if _, err := conn.Write(req); err != nil {
+		return false, false
+	}
+	resp := make([]byte, 1)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return false, false
+	}
+	switch resp[0] {
+	case 'S':
+		return true, true
+	case 'N':
+		return false, true
+	}
+	return false, false
+}
+
+// startup sends a cleartext StartupMessage for the postgres user and
+// returns the type and body of the first message the server answers
+// with, or a nil body when it does not.
+func startup(s *predictors.Session, protocol uint32) (byte, []byte) {
+	conn, err := s.Dial()
+	if err != nil {
+		return 0, nil
+	}
+	defer conn.Close()
+
+	params := "user\x00postgres\x00database\x00postgres\x00application_name\x00port-scanner\x00\x00"
+	msg := binary.BigEndian.AppendUint32(nil, uint32(8+len(params)))
+	msg = binary.BigEndian.AppendUint32(msg, protocol)
+	msg = append(msg, params...)
+	if _, err := conn.Write(msg); err != nil {
+		return 0, nil
+	}
+	return readMessage(s, conn)
+}
+
+func readMessage(s *predictors.Session, conn net.Conn) (byte, []byte) {
+	r := s.Limit(conn)
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size < 4 || size > maxMessage {
+		return 0, nil
+	}
+	body := make([]byte, size-4)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil
+	}
+	return header[0], body
+}
+
+// errorMessage returns the M field of an ErrorResponse body.
+func errorMessage(body []byte) string {
+	for _, field := range strings.Split(string(body), "\x00") {
+		if strings.HasPrefix(field, "M") {
+			return field[1:]
+		}
+	}
+	return ""
+}
+
+// requiresSSL tells whether a startup error is pg_hba.conf turning a
+// cleartext connection away: "no encryption" since 12, "SSL off" before.
+func requiresSSL(message string) bool {
+	return strings.Contains(message, "pg_hba.conf") &&
+		(strings.Contains(message, "no encryption") || strings.Contains(message, "SSL off"))
+}
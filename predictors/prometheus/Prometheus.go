@@ -0,0 +1,87 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// PrometheusPredictor recognizes the Prometheus server, from its build
+// info API or its UI, and node_exporter, from its landing page. Any other
+// endpoint serving the exposition format on /metrics is reported as an
+// exporter. Metrics name hosts, disks, interfaces and jobs, so an
+// endpoint anyone can read is worth flagging.
+type PrometheusPredictor struct {
+	predictors.BasePredictor
+}
+
+var versionPattern = regexp.MustCompile(`version=([0-9][^,)\s]*)`)
+
+func (p *PrometheusPredictor) Ports() []int {
+	return []int{9090, 9100}
+}
+
+func (p *PrometheusPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *PrometheusPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+func (p *PrometheusPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	match := p.identify(s)
+	if match.Found() {
+		match.Unauthenticated = s.Authorization == ""
+	}
+	return match
+}
+
+func (p *PrometheusPredictor) identify(s *predictors.Session) predictors.Match {
+	var info struct {
+		Status string
+		Data   struct {
+			Version string
+		}
+	}
+	if resp, err := s.Get("/api/v1/status/buildinfo"); err != nil {
+		return predictors.Match{}
+	} else if resp.StatusCode == http.StatusOK && json.Unmarshal([]byte(resp.Body), &info) == nil && info.Data.Version != "" {
+		match := predictors.Certain("Prometheus " + info.Data.Version)
+		match.Software = predictors.Software{Product: "Prometheus", Version: info.Data.Version}
+		return match
+	}
+
+	if resp, err := s.Get("/"); err == nil && resp.StatusCode == http.StatusOK {
+		body := resp.Body
+		switch {
+		case strings.Contains(body, "Node Exporter"):
+			match := predictors.Certain("node_exporter")
+			match.Software = predictors.Software{Product: "node_exporter"}
+			if m := versionPattern.FindStringSubmatch(body); m != nil {
+				match.Description += " " + m[1]
+				match.Software.Version = m[1]
+			}
+			return match
+		case strings.Contains(body, "<title>Prometheus"):
+			match := predictors.Certain("Prometheus")
+			match.Software = predictors.Software{Product: "Prometheus"}
+			return match
+		}
+	}
+
+	// Exporters serve text/plain; version=0.0.4 or OpenMetrics.
+	if resp, err := s.HTTP("GET", "/metrics"); err == nil {
+		contentType := predictors.HeaderValue(resp, "Content-Type")
+		if strings.Contains(contentType, "version=0.0.4") || strings.HasPrefix(contentType, "application/openmetrics-text") {
+			return predictors.Certain("Prometheus exporter")
+		}
+	}
+	return predictors.Match{}
+}
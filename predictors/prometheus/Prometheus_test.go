@@ -0,0 +1,30 @@
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+func TestPredictMatchOverTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/status/buildinfo" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":{"version":"2.45.0"}}`))
+	}))
+	defer srv.Close()
+
+	s := predictors.NewSession(strings.TrimPrefix(srv.URL, "https://"), time.Second)
+	defer s.Close()
+	s.TLS = true
+	match := (&PrometheusPredictor{}).PredictMatch(s)
+	if match.Description != "Prometheus 2.45.0" || !match.Unauthenticated {
+		t.Fatalf("PredictMatch = %+v, want unauthenticated Prometheus 2.45.0", match)
+	}
+}
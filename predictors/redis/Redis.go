@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// RedisPredictor sends PING without authenticating. A PONG means the
+// instance accepts commands from anyone; its version is then read from
+// INFO server.
+type RedisPredictor struct {
+	predictors.BasePredictor
+}
+
+func (p *RedisPredictor) Ports() []int {
+	return []int{6379}
+}
+
+func (p *RedisPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *RedisPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+func (p *RedisPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	conn, err := s.Dial()
+	if err != nil {
+		return predictors.Match{}
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(s.Limit(conn))
+	if _, err := conn.Write([]byte("PING\r\n")); err != nil {
+		return predictors.Match{}
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return predictors.Match{}
+	}
+	line = strings.TrimSpace(line)
+
+	switch {
+	case strings.HasPrefix(line, "+PONG"):
+	case strings.HasPrefix(line, "-NOAUTH"), strings.HasPrefix(line, "-ERR operation not permitted"):
+		return predictors.Certain("Redis (auth required)")
+	default:
+		return predictors.Match{}
+	}
+
+	match := predictors.Certain("Redis (unauthenticated)")
+	match.Unauthenticated = true
+	if version := serverVersion(conn, r); version != "" {
+		match.Description = "Redis " + version + " (unauthenticated)"
+		match.Software = predictors.Software{Product: "Redis", Version: version}
+	}
+	return match
+}
+
+// serverVersion reads redis_version from the bulk reply of INFO server.
+func serverVersion(w io.Writer, r *bufio.Reader) string {
+	if _, err := w.Write([]byte("INFO server\r\n")); err != nil {
+		return ""
+	}
+	header, err := r.ReadString('\n')
+	if err != nil || !strings.HasPrefix(header, "$") {
+		return ""
+	}
+	size, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+	if err != nil || size <= 0 {
+		return ""
+	}
+
+	// The payload may be cut short by the response limit; whatever was
+	// read is enough as redis_version comes first.
+	body := make([]byte, size)
+	n, _ := io.ReadFull(r, body)
+	for _, line := range strings.Split(string(body[:n]), "\n") {
+		if version, ok := strings.CutPrefix(strings.TrimSpace(line), "redis_version:"); ok {
+			return version
+		}
+	}
+	return ""
+}
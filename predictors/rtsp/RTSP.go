@@ -0,0 +1,85 @@
+package rtsp
+
+import (
+	"bufio"
+	"fmt"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// RTSPPredictor sends "OPTIONS * RTSP/1.0" and reads the Server header and
+// the Public list of methods from the answer, which is usually enough to
+// tell an IP camera from a media server.
+type RTSPPredictor struct {
+	predictors.BasePredictor
+}
+
+func (p *RTSPPredictor) Ports() []int {
+	return []int{554, 8554}
+}
+
+func (p *RTSPPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *RTSPPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+func (p *RTSPPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	conn, err := s.Dial()
+	if err != nil {
+		return predictors.Match{}
+	}
+	defer conn.Close()
+
+	// Every request must carry a CSeq, which the answer echoes.
+	req := "OPTIONS * RTSP/1.0\r\nCSeq: 1\r\n"
+	if s.UserAgent != "" {
+		req += "User-Agent: " + s.UserAgent + "\r\n"
+	}
+	if _, err := conn.Write([]byte(req + "\r\n")); err != nil {
+		return predictors.Match{}
+	}
+
+	r := textproto.NewReader(bufio.NewReader(s.Limit(conn)))
+	status, err := r.ReadLine()
+	if err != nil || !strings.HasPrefix(status, "RTSP/1.") {
+		return predictors.Match{}
+	}
+	header, err := r.ReadMIMEHeader()
+	if err != nil && len(header) == 0 {
+		return predictors.Certain("RTSP")
+	}
+
+	var details []string
+	sw := predictors.ParseServerHeader(header.Get("Server"))
+	if sw.Known() {
+		details = append(details, sw.Product)
+	}
+	if methods := header.Get("Public"); methods != "" {
+		details = append(details, "methods: "+strings.Join(splitList(methods), ", "))
+	}
+
+	match := predictors.Certain("RTSP")
+	if len(details) > 0 {
+		match.Description = fmt.Sprintf("RTSP (%s)", strings.Join(details, ", "))
+	}
+	match.Software = sw
+	return match
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
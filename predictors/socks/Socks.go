@@ -0,0 +1,59 @@
+package socks
+
+import (
+	"io"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+const (
+	socks5Version  = 0x05
+	methodNoAuth   = 0x00
+	methodUserPass = 0x02
+	methodNone     = 0xff
+)
+
+// SocksPredictor detects SOCKS5 proxies from their method selection reply.
+// It only sends the greeting: no CONNECT is ever issued, so nothing is
+// relayed through the proxy. SOCKS4 has no greeting of its own and cannot
+// be identified without a CONNECT request, so it is not probed.
+type SocksPredictor struct {
+	predictors.BasePredictor
+}
+
+func (p *SocksPredictor) Ports() []int {
+	return []int{1080}
+}
+
+func (p *SocksPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *SocksPredictor) PredictSession(s *predictors.Session) string {
+	conn, err := s.Dial()
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	greeting := []byte{socks5Version, 2, methodNoAuth, methodUserPass}
+	if _, err := conn.Write(greeting); err != nil {
+		return ""
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(s.Limit(conn), reply); err != nil || reply[0] != socks5Version {
+		return ""
+	}
+
+	switch reply[1] {
+	case methodNoAuth:
+		return "SOCKS5 proxy (no auth)"
+	case methodUserPass, methodNone:
+		return "SOCKS5 proxy (auth required)"
+	}
+	return "SOCKS5 proxy"
+}
@@ -1,13 +1,10 @@
 package webserver
 
 import (
-	"io"
-	"net"
 	"strings"
 	"time"
 
 	"github.com/elchemista/port-scanner/predictors"
-	// "fmt"
 )
 
 type NginxPredictor struct {
@@ -15,32 +12,25 @@ type NginxPredictor struct {
 }
 
 func (p *NginxPredictor) Predict(host string) string {
-	duration, _ := time.ParseDuration("3s")
-
-	tcpAddr, err := net.ResolveTCPAddr("tcp4", host)
-	if err != nil {
-		return ""
-	}
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
 
-	conn, err := net.DialTCP("tcp", nil, tcpAddr)
-	if err != nil {
-		return ""
-	}
-	defer conn.Close()
-	conn.SetDeadline(time.Now().Add(duration))
+func (p *NginxPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
 
-	_, err = conn.Write([]byte("HEAD / HTTP/1.0\r\n\r\n"))
+func (p *NginxPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	resp, err := s.HTTP("HEAD", "/")
 	if err != nil {
-		return ""
+		return predictors.Match{}
 	}
-
-	result, err := io.ReadAll(conn)
-	if err != nil {
-		return ""
+	match := predictors.Certain(p.PredictResponse(resp, p))
+	if match.Found() {
+		match.Software = predictors.ParseServerHeader(predictors.HeaderValue(resp, "Server"))
 	}
-
-	resp := string(result)
-	return p.PredictResponse(resp, p)
+	return match
 }
 
 func (p *NginxPredictor) PredictResponseDetail(resp string) string {
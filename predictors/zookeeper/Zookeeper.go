@@ -0,0 +1,68 @@
+package zookeeper
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/elchemista/port-scanner/predictors"
+)
+
+// ZookeeperPredictor sends the "srvr" four letter word and reads the
+// version line of the reply.
+type ZookeeperPredictor struct {
+	predictors.BasePredictor
+}
+
+func (p *ZookeeperPredictor) Ports() []int {
+	return []int{2181}
+}
+
+func (p *ZookeeperPredictor) Predict(host string) string {
+	s := predictors.NewSession(host, 3*time.Second)
+	defer s.Close()
+	return p.PredictSession(s)
+}
+
+func (p *ZookeeperPredictor) PredictSession(s *predictors.Session) string {
+	return p.PredictMatch(s).Description
+}
+
+func (p *ZookeeperPredictor) PredictMatch(s *predictors.Session) predictors.Match {
+	conn, err := s.Dial()
+	if err != nil {
+		return predictors.Match{}
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("srvr")); err != nil {
+		return predictors.Match{}
+	}
+	// The server closes the connection once it has answered.
+	resp, err := io.ReadAll(s.Limit(conn))
+	if err != nil && len(resp) == 0 {
+		return predictors.Match{}
+	}
+	return p.parse(string(resp))
+}
+
+func (p *ZookeeperPredictor) parse(resp string) predictors.Match {
+	// Since 3.5 only whitelisted four letter words are answered.
+	if strings.Contains(resp, "not in the whitelist") || strings.Contains(resp, "not executed") {
+		return predictors.Certain("Zookeeper (4lw restricted)")
+	}
+
+	for _, line := range strings.Split(resp, "\n") {
+		version, ok := strings.CutPrefix(strings.TrimSpace(line), "Zookeeper version:")
+		if !ok {
+			continue
+		}
+		// "3.9.1-<commit>, built on <date>"
+		version, _, _ = strings.Cut(strings.TrimSpace(version), ",")
+		version, _, _ = strings.Cut(version, "-")
+		match := predictors.Certain("Zookeeper " + version)
+		match.Software = predictors.Software{Product: "Zookeeper", Version: version}
+		return match
+	}
+	return predictors.Match{}
+}